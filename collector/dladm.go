@@ -0,0 +1,144 @@
+// dladm collector
+// this will :
+//  - call dladm show-phys
+//  - gather physical link metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// GZPhysLinkCollector declares the data type within the prometheus metrics
+// package.
+type GZPhysLinkCollector struct {
+	gzPhysLinkInfo  *prometheus.GaugeVec
+	gzPhysLinkSpeed *prometheus.GaugeVec
+	gzPhysLinkState *prometheus.GaugeVec
+}
+
+// NewGZPhysLinkExporter returns a newly allocated exporter GZPhysLinkCollector.
+// It exposes the state, speed and duplex of the physical links.
+func NewGZPhysLinkExporter() (*GZPhysLinkCollector, error) {
+	return &GZPhysLinkCollector{
+		gzPhysLinkInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_phys_info",
+			Help: "Physical link media, duplex and device; always 1.",
+		}, []string{"link", "media", "duplex", "device"}),
+		gzPhysLinkSpeed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_phys_speed_bits",
+			Help: "Physical link negotiated speed in bits per second.",
+		}, []string{"link"}),
+		gzPhysLinkState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_phys_link_state",
+			Help: "Physical link state; 0 for down, 1 for up.",
+		}, []string{"link"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZPhysLinkCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzPhysLinkInfo.Describe(ch)
+	e.gzPhysLinkSpeed.Describe(ch)
+	e.gzPhysLinkState.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZPhysLinkCollector) Collect(ch chan<- prometheus.Metric) {
+	e.dladmShowPhys()
+	e.gzPhysLinkInfo.Collect(ch)
+	e.gzPhysLinkSpeed.Collect(ch)
+	e.gzPhysLinkState.Collect(ch)
+}
+
+func (e *GZPhysLinkCollector) dladmShowPhys() {
+	out, eerr := exec.Command("dladm", "show-phys", "-p", "-o", "link,media,state,speed,duplex,device").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	perr := e.parseDladmShowPhysOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing dladm show-phys: %v", perr)
+	}
+}
+
+func (e *GZPhysLinkCollector) parseDladmShowPhysOutput(out string) error {
+	// links come and go with the hardware and their configuration
+	e.gzPhysLinkInfo.Reset()
+	e.gzPhysLinkSpeed.Reset()
+	e.gzPhysLinkState.Reset()
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 6 {
+			continue
+		}
+		link := parsedLine[0]
+		speedBits, err := parseDladmSpeed(parsedLine[3])
+		if err != nil {
+			return err
+		}
+
+		e.gzPhysLinkInfo.With(prometheus.Labels{
+			"link": link, "media": parsedLine[1], "duplex": parsedLine[4], "device": parsedLine[5],
+		}).Set(1)
+		e.gzPhysLinkSpeed.With(prometheus.Labels{"link": link}).Set(speedBits)
+		e.gzPhysLinkState.With(prometheus.Labels{"link": link}).Set(dladmLinkState(parsedLine[2]))
+	}
+	return nil
+}
+
+// parseDladmLine splits a line of dladm parseable (-p) output into its
+// fields. Colons and backslashes which are part of a value (e.g. a MAC
+// address) are escaped with a backslash.
+func parseDladmLine(line string) []string {
+	if line == "" {
+		return nil
+	}
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// parseDladmSpeed converts a dladm speed in Mb/s (e.g. "10000" or "10000Mb")
+// into bits per second. Unknown speeds are reported as 0.
+func parseDladmSpeed(speed string) (float64, error) {
+	speed = strings.TrimSuffix(speed, "Mb")
+	if speed == "" || speed == "unknown" || speed == "--" {
+		return 0, nil
+	}
+	speedMb, err := strconv.ParseFloat(speed, 64)
+	if err != nil {
+		return 0, err
+	}
+	return speedMb * 1000 * 1000, nil
+}
+
+// dladmLinkState maps a dladm link state; 0 for down, 1 for up.
+func dladmLinkState(state string) float64 {
+	if state == "up" {
+		return 1
+	}
+	return 0
+}
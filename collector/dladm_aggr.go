@@ -0,0 +1,219 @@
+// dladm aggr collector
+// this will :
+//  - call dladm show-aggr (extended and LACP views)
+//  - gather link aggregation metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// gzAggrLACPFlags maps the dladm show-aggr -L fields to their flag label.
+var gzAggrLACPFlags = []string{
+	"aggregatable",
+	"sync",
+	"collecting",
+	"distributing",
+	"defaulted",
+	"expired",
+}
+
+// GZAggrCollector declares the data type within the prometheus metrics
+// package.
+type GZAggrCollector struct {
+	gzAggrInfo          *prometheus.GaugeVec
+	gzAggrLinkState     *prometheus.GaugeVec
+	gzAggrPorts         *prometheus.GaugeVec
+	gzAggrPortAttached  *prometheus.GaugeVec
+	gzAggrPortLACPFlag  *prometheus.GaugeVec
+	gzAggrPortLinkState *prometheus.GaugeVec
+	gzAggrPortSpeed     *prometheus.GaugeVec
+	gzAggrSpeed         *prometheus.GaugeVec
+}
+
+// NewGZAggrExporter returns a newly allocated exporter GZAggrCollector.
+// It exposes the state of every link aggregation and of its member ports.
+func NewGZAggrExporter() (*GZAggrCollector, error) {
+	return &GZAggrCollector{
+		gzAggrInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_info",
+			Help: "Link aggregation policy and LACP mode; always 1.",
+		}, []string{"aggr", "policy", "lacp_activity", "lacp_timer"}),
+		gzAggrLinkState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_link_state",
+			Help: "Link aggregation state; 0 for down, 1 for up.",
+		}, []string{"aggr"}),
+		gzAggrPorts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_ports",
+			Help: "Number of member ports of the link aggregation.",
+		}, []string{"aggr"}),
+		gzAggrPortAttached: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_port_attached",
+			Help: "Member port state; 0 for standby, 1 for attached.",
+		}, []string{"aggr", "port"}),
+		gzAggrPortLACPFlag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_port_lacp_flag",
+			Help: "Member port LACP state flag; 0 for no, 1 for yes.",
+		}, []string{"aggr", "port", "flag"}),
+		gzAggrPortLinkState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_port_link_state",
+			Help: "Member port link state; 0 for down, 1 for up.",
+		}, []string{"aggr", "port"}),
+		gzAggrPortSpeed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_port_speed_bits",
+			Help: "Member port speed in bits per second.",
+		}, []string{"aggr", "port"}),
+		gzAggrSpeed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_aggr_speed_bits",
+			Help: "Link aggregation speed in bits per second.",
+		}, []string{"aggr"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZAggrCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzAggrInfo.Describe(ch)
+	e.gzAggrLinkState.Describe(ch)
+	e.gzAggrPorts.Describe(ch)
+	e.gzAggrPortAttached.Describe(ch)
+	e.gzAggrPortLACPFlag.Describe(ch)
+	e.gzAggrPortLinkState.Describe(ch)
+	e.gzAggrPortSpeed.Describe(ch)
+	e.gzAggrSpeed.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZAggrCollector) Collect(ch chan<- prometheus.Metric) {
+	e.dladmShowAggr()
+	e.gzAggrInfo.Collect(ch)
+	e.gzAggrLinkState.Collect(ch)
+	e.gzAggrPorts.Collect(ch)
+	e.gzAggrPortAttached.Collect(ch)
+	e.gzAggrPortLACPFlag.Collect(ch)
+	e.gzAggrPortLinkState.Collect(ch)
+	e.gzAggrPortSpeed.Collect(ch)
+	e.gzAggrSpeed.Collect(ch)
+}
+
+func (e *GZAggrCollector) dladmShowAggr() {
+	// member ports come and go with the aggregation configuration
+	e.gzAggrInfo.Reset()
+	e.gzAggrLinkState.Reset()
+	e.gzAggrPorts.Reset()
+	e.gzAggrPortAttached.Reset()
+	e.gzAggrPortLACPFlag.Reset()
+	e.gzAggrPortLinkState.Reset()
+	e.gzAggrPortSpeed.Reset()
+	e.gzAggrSpeed.Reset()
+
+	out, eerr := exec.Command("dladm", "show-aggr", "-p", "-o", "link,policy,lacpactivity,lacptimer").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	perr := e.parseDladmShowAggrOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing dladm show-aggr: %v", perr)
+	}
+
+	out, eerr = exec.Command("dladm", "show-aggr", "-x", "-p", "-o", "link,port,speed,state,portstate").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	perr = e.parseDladmShowAggrExtendedOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing dladm show-aggr -x: %v", perr)
+	}
+
+	out, eerr = exec.Command("dladm", "show-aggr", "-L", "-p", "-o",
+		"link,port,aggregatable,sync,coll,dist,defaulted,expired").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	perr = e.parseDladmShowAggrLACPOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing dladm show-aggr -L: %v", perr)
+	}
+}
+
+func (e *GZAggrCollector) parseDladmShowAggrOutput(out string) error {
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 4 {
+			continue
+		}
+		e.gzAggrInfo.With(prometheus.Labels{
+			"aggr": parsedLine[0], "policy": parsedLine[1], "lacp_activity": parsedLine[2], "lacp_timer": parsedLine[3],
+		}).Set(1)
+	}
+	return nil
+}
+
+func (e *GZAggrCollector) parseDladmShowAggrExtendedOutput(out string) error {
+	// the aggregation line comes first (without port), followed by
+	// its member ports (without link)
+	aggr := ""
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 5 {
+			continue
+		}
+		if parsedLine[0] != "" {
+			aggr = parsedLine[0]
+		}
+		port := parsedLine[1]
+		speedBits, err := parseDladmSpeed(parsedLine[2])
+		if err != nil {
+			return err
+		}
+		state := dladmLinkState(parsedLine[3])
+
+		if port == "" {
+			e.gzAggrSpeed.With(prometheus.Labels{"aggr": aggr}).Set(speedBits)
+			e.gzAggrLinkState.With(prometheus.Labels{"aggr": aggr}).Set(state)
+			e.gzAggrPorts.With(prometheus.Labels{"aggr": aggr}).Set(0)
+			continue
+		}
+
+		attached := 0.0
+		if parsedLine[4] == "attached" {
+			attached = 1
+		}
+		e.gzAggrPorts.With(prometheus.Labels{"aggr": aggr}).Inc()
+		e.gzAggrPortSpeed.With(prometheus.Labels{"aggr": aggr, "port": port}).Set(speedBits)
+		e.gzAggrPortLinkState.With(prometheus.Labels{"aggr": aggr, "port": port}).Set(state)
+		e.gzAggrPortAttached.With(prometheus.Labels{"aggr": aggr, "port": port}).Set(attached)
+	}
+	return nil
+}
+
+func (e *GZAggrCollector) parseDladmShowAggrLACPOutput(out string) error {
+	// the link is only printed along with the first member port
+	aggr := ""
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 2+len(gzAggrLACPFlags) {
+			continue
+		}
+		if parsedLine[0] != "" {
+			aggr = parsedLine[0]
+		}
+		port := parsedLine[1]
+		for i, flag := range gzAggrLACPFlags {
+			value := 0.0
+			if parsedLine[2+i] == "yes" {
+				value = 1
+			}
+			e.gzAggrPortLACPFlag.With(prometheus.Labels{"aggr": aggr, "port": port, "flag": flag}).Set(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// dladm bandwidth collector
+// this will :
+//  - call dladm show-linkprop, flowadm and flowstat inside the GZ
+//  - gather VNIC bandwidth limits and flow metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// gzLinkPriorities maps the priority link property to its value.
+var gzLinkPriorities = map[string]float64{
+	"low":    0,
+	"medium": 1,
+	"high":   2,
+}
+
+// GZBandwidthCollector declares the data type within the prometheus metrics
+// package.
+type GZBandwidthCollector struct {
+	gzFlowIErrors  *kstatVec
+	gzFlowIPackets *kstatVec
+	gzFlowOBytes   *kstatVec
+	gzFlowOErrors  *kstatVec
+	gzFlowOPackets *kstatVec
+	gzFlowRBytes   *kstatVec
+	gzVNICMaxBW    *prometheus.GaugeVec
+	gzVNICPriority *prometheus.GaugeVec
+}
+
+// NewGZBandwidthExporter returns a newly allocated exporter GZBandwidthCollector.
+// It exposes the bandwidth limit and priority of the VNICs of every zone, and
+// the counters of the configured flows.
+func NewGZBandwidthExporter() (*GZBandwidthCollector, error) {
+	return &GZBandwidthCollector{
+		gzFlowIErrors: newKstatVec("smartos_network_flow_receive_errs_total",
+			"Flow packets received in error or dropped.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzFlowIPackets: newKstatVec("smartos_network_flow_receive_packets_total",
+			"Flow packets received.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzFlowOBytes: newKstatVec("smartos_network_flow_transmit_bytes_total",
+			"Flow bytes (octets) transmitted.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzFlowOErrors: newKstatVec("smartos_network_flow_transmit_errs_total",
+			"Flow packets not transmitted due to an error or a drop.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzFlowOPackets: newKstatVec("smartos_network_flow_transmit_packets_total",
+			"Flow packets transmitted.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzFlowRBytes: newKstatVec("smartos_network_flow_receive_bytes_total",
+			"Flow bytes (octets) received.",
+			prometheus.CounterValue, []string{"flow", "link"}),
+		gzVNICMaxBW: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_vnic_maxbw_bits",
+			Help: "VNIC bandwidth limit (maxbw) in bits per second.",
+		}, []string{"zonename", "device"}),
+		gzVNICPriority: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_vnic_priority",
+			Help: "VNIC priority; 0 for low, 1 for medium, 2 for high.",
+		}, []string{"zonename", "device"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZBandwidthCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzFlowIErrors.Describe(ch)
+	e.gzFlowIPackets.Describe(ch)
+	e.gzFlowOBytes.Describe(ch)
+	e.gzFlowOErrors.Describe(ch)
+	e.gzFlowOPackets.Describe(ch)
+	e.gzFlowRBytes.Describe(ch)
+	e.gzVNICMaxBW.Describe(ch)
+	e.gzVNICPriority.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZBandwidthCollector) Collect(ch chan<- prometheus.Metric) {
+	e.dladmShowLinkprop()
+	e.flowstat()
+	e.gzFlowIErrors.Collect(ch)
+	e.gzFlowIPackets.Collect(ch)
+	e.gzFlowOBytes.Collect(ch)
+	e.gzFlowOErrors.Collect(ch)
+	e.gzFlowOPackets.Collect(ch)
+	e.gzFlowRBytes.Collect(ch)
+	e.gzVNICMaxBW.Collect(ch)
+	e.gzVNICPriority.Collect(ch)
+}
+
+func (e *GZBandwidthCollector) dladmShowLinkprop() {
+	// VNICs come and go with the zones
+	e.gzVNICMaxBW.Reset()
+	e.gzVNICPriority.Reset()
+
+	out, eerr := exec.Command("dladm", "show-vnic", "-p", "-o", "link,over,vid,macaddress,zone").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	zones := make(map[string]bool)
+	for k := range parseDladmShowVNICOutput(string(out)) {
+		zones[k.zoneName] = true
+	}
+
+	// link names are only unique within a zone, the VNICs of the GZ
+	// have no zone
+	for zoneName := range zones {
+		args := []string{"show-linkprop", "-c", "-p", "maxbw,priority", "-o", "link,property,value"}
+		if zoneName == "" || zoneName == "--" {
+			zoneName = "global"
+		} else {
+			args = append(args, "-z", zoneName)
+		}
+		out, eerr := exec.Command("dladm", args...).Output()
+		if eerr != nil {
+			log.Errorf("error on executing dladm show-linkprop in zone %s: %v", zoneName, eerr)
+			continue
+		}
+		perr := e.parseDladmShowLinkpropOutput(zoneName, string(out))
+		if perr != nil {
+			log.Errorf("error on parsing dladm show-linkprop: %v", perr)
+		}
+	}
+}
+
+func (e *GZBandwidthCollector) parseDladmShowLinkpropOutput(zoneName string, out string) error {
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 3 || parsedLine[2] == "" || parsedLine[2] == "--" {
+			continue
+		}
+		link := parsedLine[0]
+		if i := strings.LastIndex(link, "/"); i >= 0 {
+			link = link[i+1:]
+		}
+		labels := prometheus.Labels{"zonename": zoneName, "device": link}
+		switch parsedLine[1] {
+		case "maxbw":
+			maxBW, err := parseDladmBandwidth(parsedLine[2])
+			if err != nil {
+				return err
+			}
+			e.gzVNICMaxBW.With(labels).Set(maxBW)
+		case "priority":
+			if priority, ok := gzLinkPriorities[parsedLine[2]]; ok {
+				e.gzVNICPriority.With(labels).Set(priority)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *GZBandwidthCollector) flowstat() {
+	// flows come and go with the configuration
+	e.gzFlowIErrors.Reset()
+	e.gzFlowIPackets.Reset()
+	e.gzFlowOBytes.Reset()
+	e.gzFlowOErrors.Reset()
+	e.gzFlowOPackets.Reset()
+	e.gzFlowRBytes.Reset()
+
+	out, eerr := exec.Command("flowadm", "show-flow", "-p", "-o", "flow,link").Output()
+	if eerr != nil {
+		log.Errorf("error on executing flowadm: %v", eerr)
+		return
+	}
+	links := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) == 2 {
+			links[parsedLine[0]] = parsedLine[1]
+		}
+	}
+	// nothing to report without flows
+	if len(links) == 0 {
+		return
+	}
+
+	out, eerr = exec.Command("flowstat", "-p", "-o", "flow,ipkts,rbytes,ierrs,opkts,obytes,oerrs").Output()
+	if eerr != nil {
+		log.Errorf("error on executing flowstat: %v", eerr)
+		return
+	}
+	perr := e.parseFlowstatOutput(string(out), links)
+	if perr != nil {
+		log.Errorf("error on parsing flowstat: %v", perr)
+	}
+}
+
+func (e *GZBandwidthCollector) parseFlowstatOutput(out string, links map[string]string) error {
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 7 {
+			continue
+		}
+		var values [6]float64
+		for i := range values {
+			value, err := strconv.ParseFloat(parsedLine[i+1], 64)
+			if err != nil {
+				return err
+			}
+			values[i] = value
+		}
+		labels := prometheus.Labels{"flow": parsedLine[0], "link": links[parsedLine[0]]}
+		e.gzFlowIPackets.Set(labels, values[0])
+		e.gzFlowRBytes.Set(labels, values[1])
+		e.gzFlowIErrors.Set(labels, values[2])
+		e.gzFlowOPackets.Set(labels, values[3])
+		e.gzFlowOBytes.Set(labels, values[4])
+		e.gzFlowOErrors.Set(labels, values[5])
+	}
+	return nil
+}
+
+// parseDladmBandwidth converts a dladm bandwidth (e.g. "100", "1.5G" or
+// "500K"), in Mb/s unless a unit is given, into bits per second.
+func parseDladmBandwidth(bw string) (float64, error) {
+	unit := 1000.0 * 1000
+	switch {
+	case strings.HasSuffix(bw, "K"):
+		unit = 1000
+	case strings.HasSuffix(bw, "M"):
+		unit = 1000 * 1000
+	case strings.HasSuffix(bw, "G"):
+		unit = 1000 * 1000 * 1000
+	}
+	value, err := strconv.ParseFloat(strings.TrimRight(bw, "KMG"), 64)
+	if err != nil {
+		return 0, err
+	}
+	return value * unit, nil
+}
@@ -0,0 +1,182 @@
+// dladm overlay collector
+// this will :
+//  - call dladm show-overlay and kstat inside the GZ
+//  - gather overlay (VXLAN fabric) network metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// overlayKstatStats lists the link statistics of the overlay devices exposed
+// by the overlay collector. The overlay driver keeps no statistics of its
+// own; the link ones account for the encapsulated traffic on transmit and
+// for the decapsulated traffic on receive. The target lookup misses are
+// handled by varpd and are not accounted for in any kstat.
+var overlayKstatStats = []kstatStat{
+	{"obytes64", "encap_bytes_total", "Bytes (octets) encapsulated.", prometheus.CounterValue},
+	{"opackets64", "encap_packets_total", "Frames encapsulated.", prometheus.CounterValue},
+	{"oerrors", "encap_errs_total", "Encapsulation errors.", prometheus.CounterValue},
+	{"rbytes64", "decap_bytes_total", "Bytes (octets) decapsulated.", prometheus.CounterValue},
+	{"ipackets64", "decap_packets_total", "Frames decapsulated.", prometheus.CounterValue},
+	{"ierrors", "decap_errs_total", "Decapsulation errors.", prometheus.CounterValue},
+}
+
+// GZOverlayCollector declares the data type within the prometheus metrics
+// package.
+type GZOverlayCollector struct {
+	gzOverlayInfo  *prometheus.GaugeVec
+	gzOverlayKstat map[string]*kstatVec
+	gzOverlayState *prometheus.GaugeVec
+}
+
+// GZOverlay defines the mapping of the dladm show-overlay properties of an
+// overlay.
+type GZOverlay struct {
+	vnetid, encap, search string
+}
+
+// NewGZOverlayExporter returns a newly allocated exporter GZOverlayCollector.
+// It exposes the state and the encapsulation and decapsulation counters of
+// the overlay devices.
+func NewGZOverlayExporter() (*GZOverlayCollector, error) {
+	return &GZOverlayCollector{
+		gzOverlayInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_overlay_info",
+			Help: "Overlay encapsulation and search plugins; always 1.",
+		}, []string{"device", "vnetid", "encap", "search"}),
+		gzOverlayKstat: newKstatVecs(overlayKstatStats, "smartos_network_overlay_", []string{"device", "vnetid"}),
+		gzOverlayState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_network_overlay_state",
+			Help: "Overlay state; 0 for degraded, 1 for online.",
+		}, []string{"device", "vnetid"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZOverlayCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzOverlayInfo.Describe(ch)
+	for _, s := range overlayKstatStats {
+		e.gzOverlayKstat[s.stat].Describe(ch)
+	}
+	e.gzOverlayState.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZOverlayCollector) Collect(ch chan<- prometheus.Metric) {
+	e.dladmShowOverlay()
+	e.gzOverlayInfo.Collect(ch)
+	for _, s := range overlayKstatStats {
+		e.gzOverlayKstat[s.stat].Collect(ch)
+	}
+	e.gzOverlayState.Collect(ch)
+}
+
+func (e *GZOverlayCollector) dladmShowOverlay() {
+	// overlays come and go with the fabric networks
+	e.gzOverlayInfo.Reset()
+	for _, vec := range e.gzOverlayKstat {
+		vec.Reset()
+	}
+	e.gzOverlayState.Reset()
+
+	out, eerr := exec.Command("dladm", "show-overlay", "-p", "-o", "link,property,value").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	overlays := parseDladmShowOverlayOutput(string(out))
+	for link, overlay := range overlays {
+		e.gzOverlayInfo.With(prometheus.Labels{
+			"device": link, "vnetid": overlay.vnetid, "encap": overlay.encap, "search": overlay.search,
+		}).Set(1)
+	}
+
+	out, eerr = exec.Command("dladm", "show-overlay", "-f", "-p", "-o", "link,status").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	perr := e.parseDladmShowOverlayStatusOutput(string(out), overlays)
+	if perr != nil {
+		log.Errorf("error on parsing dladm show-overlay -f: %v", perr)
+	}
+
+	out, eerr = exec.Command("kstat", "-p", "-m", "link").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr = e.parseKstatOverlayListOutput(string(out), overlays)
+	if perr != nil {
+		log.Errorf("error on parsing kstat overlay list: %v", perr)
+	}
+}
+
+func (e *GZOverlayCollector) parseDladmShowOverlayStatusOutput(out string, overlays map[string]*GZOverlay) error {
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 2 {
+			continue
+		}
+		overlay, ok := overlays[parsedLine[0]]
+		if !ok {
+			continue
+		}
+		state := 0.0
+		if strings.EqualFold(parsedLine[1], "online") {
+			state = 1
+		}
+		e.gzOverlayState.With(prometheus.Labels{"device": parsedLine[0], "vnetid": overlay.vnetid}).Set(state)
+	}
+	return nil
+}
+
+func (e *GZOverlayCollector) parseKstatOverlayListOutput(out string, overlays map[string]*GZOverlay) error {
+	for _, k := range parseKstatOutput(out) {
+		if zoneName, ok := k.stats["zonename"]; ok && zoneName != "global" {
+			continue
+		}
+		overlay, ok := overlays[k.name]
+		if !ok {
+			continue
+		}
+		labels := prometheus.Labels{"device": k.name, "vnetid": overlay.vnetid}
+		if err := setKstatVecs(e.gzOverlayKstat, overlayKstatStats, k, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDladmShowOverlayOutput maps each overlay of
+// dladm show-overlay -p -o link,property,value to its properties.
+func parseDladmShowOverlayOutput(out string) map[string]*GZOverlay {
+	overlays := make(map[string]*GZOverlay)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 3 {
+			continue
+		}
+		overlay, ok := overlays[parsedLine[0]]
+		if !ok {
+			overlay = &GZOverlay{}
+			overlays[parsedLine[0]] = overlay
+		}
+		switch parsedLine[1] {
+		case "vnetid":
+			overlay.vnetid = parsedLine[2]
+		case "encap":
+			overlay.encap = parsedLine[2]
+		case "search":
+			overlay.search = parsedLine[2]
+		}
+	}
+	return overlays
+}
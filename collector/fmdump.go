@@ -0,0 +1,142 @@
+// fmdump collector
+// this will :
+//  - call fmdump on the FMA error log (and its last rotation)
+//  - gather ereport telemetry incrementally
+//  - feed the collector
+
+package collector
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// fmdump reads the current error log and the one logadm rotated last, so
+// ereports written between two scrapes are not lost across a rotation.
+var gzFMErrlogs = []string{"/var/fm/fmd/errlog.0", "/var/fm/fmd/errlog"}
+
+// GZFMErrorsCollector declares the data type within the prometheus metrics
+// package.
+type GZFMErrorsCollector struct {
+	gzFMEreports *prometheus.CounterVec
+
+	mu       sync.Mutex
+	lastSeen time.Time
+}
+
+// NewGZFMErrorsExporter returns a newly allocated exporter GZFMErrorsCollector.
+// It exposes the number of FMA ereports by class and resource.
+func NewGZFMErrorsExporter() (*GZFMErrorsCollector, error) {
+	return &GZFMErrorsCollector{
+		gzFMEreports: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "smartos_fm_ereports_total",
+			Help: "Number of FMA error reports (ereports) logged.",
+		}, []string{"class", "resource"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZFMErrorsCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzFMEreports.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZFMErrorsCollector) Collect(ch chan<- prometheus.Metric) {
+	e.fmdump()
+	e.gzFMEreports.Collect(ch)
+}
+
+func (e *GZFMErrorsCollector) fmdump() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, errlog := range gzFMErrlogs {
+		if _, serr := os.Stat(errlog); serr != nil {
+			continue
+		}
+		args := []string{"-eV"}
+		if !e.lastSeen.IsZero() {
+			// fmdump only takes a second resolution, events already
+			// counted are skipped while parsing.
+			args = append(args, "-t", e.lastSeen.Format("01/02/06 15:04:05"))
+		}
+		args = append(args, errlog)
+		out, eerr := exec.Command("fmdump", args...).Output()
+		if eerr != nil {
+			log.Errorf("error on executing fmdump: %v", eerr)
+			continue
+		}
+		perr := e.parseFmdumpOutput(string(out))
+		if perr != nil {
+			log.Errorf("error on parsing fmdump: %v", perr)
+		}
+	}
+}
+
+// GZFMEreport defines the mapping of a verbose fmdump ereport record.
+type GZFMEreport struct {
+	when                    time.Time
+	class, resource, scheme string
+}
+
+func (e *GZFMErrorsCollector) parseFmdumpOutput(out string) error {
+	// each ereport starts with its timestamp and its class
+	r, _ := regexp.Compile(`^(\w{3} +\d+ \d{4} \d{2}:\d{2}:\d{2}\.\d+) +(ereport\.\S+)$`)
+
+	var ereports []*GZFMEreport
+	var current *GZFMEreport
+	inDetector := false
+	for _, line := range strings.Split(out, "\n") {
+		if fields := r.FindStringSubmatch(line); fields != nil {
+			when, err := time.ParseInLocation("Jan 2 2006 15:04:05.999999999", fields[1], time.Local)
+			if err != nil {
+				return err
+			}
+			current = &GZFMEreport{when: when, class: fields[2]}
+			ereports = append(ereports, current)
+			inDetector = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		// the resource is taken from the detector nvlist
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "detector = "):
+			inDetector = true
+		case trimmed == "(end detector)":
+			inDetector = false
+		case inDetector && strings.HasPrefix(trimmed, "device-path = "):
+			current.resource = strings.TrimPrefix(trimmed, "device-path = ")
+		case inDetector && strings.HasPrefix(trimmed, "scheme = "):
+			current.scheme = strings.TrimPrefix(trimmed, "scheme = ")
+		}
+	}
+
+	newest := e.lastSeen
+	for _, ereport := range ereports {
+		// already counted during a previous scrape
+		if !ereport.when.After(e.lastSeen) {
+			continue
+		}
+		resource := ereport.resource
+		if resource == "" {
+			resource = ereport.scheme
+		}
+		e.gzFMEreports.With(prometheus.Labels{"class": ereport.class, "resource": resource}).Inc()
+		if ereport.when.After(newest) {
+			newest = ereport.when
+		}
+	}
+	e.lastSeen = newest
+
+	return nil
+}
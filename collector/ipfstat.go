@@ -0,0 +1,230 @@
+// ipfstat collector
+// this will :
+//  - call ipfstat (for every zone when running in the GZ)
+//  - gather IP Filter firewall metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// IPFilterCollector declares the data type within the prometheus metrics
+// package.
+type IPFilterCollector struct {
+	ipfPackets       *kstatVec
+	ipfPacketsLogged *kstatVec
+	ipfRuleHits      *kstatVec
+	ipfStateEntries  *prometheus.GaugeVec
+
+	allZones bool
+	ruleHits bool
+}
+
+// NewIPFilterExporter returns a newly allocated exporter IPFilterCollector.
+// It exposes the packets passed and blocked by IP Filter and its state table
+// usage. When ruleHits is set, the hits of every rule are reported too. When
+// allZones is set, the firewall of every running zone is reported.
+func NewIPFilterExporter(allZones bool, ruleHits bool) (*IPFilterCollector, error) {
+	return &IPFilterCollector{
+		ipfPackets: newKstatVec("smartos_ipf_packets_total",
+			"Packets seen by IP Filter by direction and action.",
+			prometheus.CounterValue, []string{"zonename", "direction", "action"}),
+		ipfPacketsLogged: newKstatVec("smartos_ipf_packets_logged_total",
+			"Packets logged by IP Filter by direction and action.",
+			prometheus.CounterValue, []string{"zonename", "direction", "action"}),
+		ipfRuleHits: newKstatVec("smartos_ipf_rule_hits_total",
+			"Packets matched by an IP Filter rule.",
+			prometheus.CounterValue, []string{"zonename", "direction", "number", "rule"}),
+		ipfStateEntries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_ipf_state_entries",
+			Help: "Active entries of the IP Filter state table.",
+		}, []string{"zonename"}),
+		allZones: allZones,
+		ruleHits: ruleHits,
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *IPFilterCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.ipfPackets.Describe(ch)
+	e.ipfPacketsLogged.Describe(ch)
+	e.ipfRuleHits.Describe(ch)
+	e.ipfStateEntries.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *IPFilterCollector) Collect(ch chan<- prometheus.Metric) {
+	e.ipfstat()
+	e.ipfPackets.Collect(ch)
+	e.ipfPacketsLogged.Collect(ch)
+	e.ipfRuleHits.Collect(ch)
+	e.ipfStateEntries.Collect(ch)
+}
+
+func (e *IPFilterCollector) ipfstat() {
+	// zones and rules come and go, start from a clean state
+	e.ipfPackets.Reset()
+	e.ipfPacketsLogged.Reset()
+	e.ipfRuleHits.Reset()
+	e.ipfStateEntries.Reset()
+
+	if !e.allZones {
+		zoneName, eerr := exec.Command("zonename").Output()
+		if eerr != nil {
+			log.Errorf("error on executing zonename: %v", eerr)
+			return
+		}
+		e.ipfstatZone(strings.TrimSpace(string(zoneName)))
+		return
+	}
+
+	out, eerr := exec.Command("zoneadm", "list", "-p").Output()
+	if eerr != nil {
+		log.Errorf("error on executing zoneadm: %v", eerr)
+		return
+	}
+	for _, z := range parseZoneadmListOutput(string(out)) {
+		// the firewall of a zone is managed from the GZ
+		if z.name == "global" {
+			e.ipfstatZone(z.name)
+		} else if z.state == "running" {
+			e.ipfstatZone(z.name, "-G", z.name)
+		}
+	}
+}
+
+func (e *IPFilterCollector) ipfstatZone(zoneName string, zoneArgs ...string) {
+	// zones without firewall have no IP Filter instance
+	out, eerr := exec.Command("ipfstat", zoneArgs...).Output()
+	if eerr != nil {
+		log.Debugf("error on executing ipfstat in zone %s: %v", zoneName, eerr)
+		return
+	}
+	perr := e.parseIpfstatOutput(zoneName, string(out))
+	if perr != nil {
+		log.Errorf("error on parsing ipfstat: %v", perr)
+	}
+
+	out, eerr = exec.Command("ipfstat", append([]string{"-s"}, zoneArgs...)...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing ipfstat -s in zone %s: %v", zoneName, eerr)
+		return
+	}
+	perr = e.parseIpfstatStateOutput(zoneName, string(out))
+	if perr != nil {
+		log.Errorf("error on parsing ipfstat -s: %v", perr)
+	}
+
+	if !e.ruleHits {
+		return
+	}
+	out, eerr = exec.Command("ipfstat", append([]string{"-hion"}, zoneArgs...)...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing ipfstat -hion in zone %s: %v", zoneName, eerr)
+		return
+	}
+	perr = e.parseIpfstatRulesOutput(zoneName, string(out))
+	if perr != nil {
+		log.Errorf("error on parsing ipfstat -hion: %v", perr)
+	}
+}
+
+func (e *IPFilterCollector) parseIpfstatOutput(zoneName string, out string) error {
+	// e.g. " input packets:		blocked 4 passed 100 nomatch 3 counted 0 short 0"
+	r, _ := regexp.Compile(`^\s*(input|output) packets( logged)?:\s+(.*)$`)
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := r.FindStringSubmatch(line)
+		if fields == nil {
+			continue
+		}
+		direction := "in"
+		if fields[1] == "output" {
+			direction = "out"
+		}
+		vec := e.ipfPackets
+		if fields[2] != "" {
+			vec = e.ipfPacketsLogged
+		}
+		// the counters are listed as "action value" pairs
+		counters := strings.Fields(fields[3])
+		for i := 0; i+1 < len(counters); i += 2 {
+			value, err := strconv.ParseFloat(counters[i+1], 64)
+			if err != nil {
+				return err
+			}
+			vec.Set(prometheus.Labels{"zonename": zoneName, "direction": direction, "action": counters[i]}, value)
+		}
+	}
+	return nil
+}
+
+func (e *IPFilterCollector) parseIpfstatStateOutput(zoneName string, out string) error {
+	r, _ := regexp.Compile(`(?m)^\s*(\d+)\s+active\s*$`)
+
+	fields := r.FindStringSubmatch(out)
+	if fields == nil {
+		return nil
+	}
+	entries, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return err
+	}
+	e.ipfStateEntries.With(prometheus.Labels{"zonename": zoneName}).Set(entries)
+	return nil
+}
+
+func (e *IPFilterCollector) parseIpfstatRulesOutput(zoneName string, out string) error {
+	// e.g. "1234 @1 pass in quick proto tcp from any to any port = 22 keep state"
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Fields(line)
+		var hits, number string
+		var rule []string
+		direction := ""
+		for _, field := range parsedLine {
+			switch {
+			case number == "" && strings.HasPrefix(field, "@"):
+				number = strings.TrimPrefix(field, "@")
+			case hits == "" && len(rule) == 0 && isDigits(field):
+				hits = field
+			default:
+				if direction == "" && (field == "in" || field == "out") {
+					direction = field
+				}
+				rule = append(rule, field)
+			}
+		}
+		if number == "" || hits == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(hits, 64)
+		if err != nil {
+			return err
+		}
+		e.ipfRuleHits.Set(prometheus.Labels{
+			"zonename": zoneName, "direction": direction, "number": number, "rule": strings.Join(rule, " "),
+		}, value)
+	}
+	return nil
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+// kstat helpers
+// this will :
+//  - parse kstat -p output
+//  - describe how kstat statistics are exposed
+
+package collector
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// kstatEntry defines the mapping of a kstat (module:instance:name) and its
+// statistics.
+type kstatEntry struct {
+	module, instance, name string
+	stats                  map[string]string
+}
+
+// parseKstatOutput parses the output of kstat -p, one
+// "module:instance:name:statistic<TAB>value" per line, into one entry per
+// kstat, in the order they are printed.
+func parseKstatOutput(out string) []*kstatEntry {
+	var entries []*kstatEntry
+	var entry *kstatEntry
+	var last string
+	for _, line := range strings.Split(out, "\n") {
+		// values may contain spaces (e.g. CPU brand), only the tab is
+		// a separator
+		parsedLine := strings.SplitN(line, "\t", 2)
+		if len(parsedLine) != 2 {
+			continue
+		}
+		fullLabel := strings.SplitN(parsedLine[0], ":", 3)
+		if len(fullLabel) != 3 {
+			continue
+		}
+		// the name itself may contain colons, the statistic does not
+		sep := strings.LastIndex(fullLabel[2], ":")
+		if sep < 0 {
+			continue
+		}
+		module, instance := fullLabel[0], fullLabel[1]
+		name, statistic := fullLabel[2][:sep], fullLabel[2][sep+1:]
+
+		// in the GZ, kstats of different zones can share the same
+		// module:instance:name (e.g. link:0:net0); the statistics of a
+		// kstat are printed sorted, a statistic which does not sort
+		// after the previous one starts a new kstat
+		if entry == nil || entry.module != module || entry.instance != instance || entry.name != name || statistic <= last {
+			entry = &kstatEntry{module, instance, name, make(map[string]string)}
+			entries = append(entries, entry)
+		}
+		entry.stats[statistic] = strings.TrimSpace(parsedLine[1])
+		last = statistic
+	}
+	return entries
+}
+
+// kstatStat defines how a kstat statistic is exposed. Monotonic statistics
+// are exposed as counters, the others as gauges.
+type kstatStat struct {
+	stat, name, help string
+	valueType        prometheus.ValueType
+}
+
+// linkKstatStats lists the link statistics exposed by the network collectors.
+var linkKstatStats = []kstatStat{
+	{"rbytes64", "receive_bytes_total", "Bytes (octets) received successfully.", prometheus.CounterValue},
+	{"obytes64", "transmit_bytes_total", "Bytes (octets) transmitted successfully.", prometheus.CounterValue},
+	{"ipackets64", "receive_packets_total", "Frames received successfully.", prometheus.CounterValue},
+	{"opackets64", "transmit_packets_total", "Frames successfully transmitted.", prometheus.CounterValue},
+	{"ierrors", "receive_errs_total", "Received errors.", prometheus.CounterValue},
+	{"oerrors", "transmit_errs_total", "Transmit errors.", prometheus.CounterValue},
+	{"norcvbuf", "receive_drop_total", "Received frames dropped for lack of buffers.", prometheus.CounterValue},
+	{"noxmtbuf", "transmit_drop_total", "Frames not transmitted for lack of buffers.", prometheus.CounterValue},
+	{"multircv", "receive_multicast_total", "Multicast frames received.", prometheus.CounterValue},
+	{"multixmt", "transmit_multicast_total", "Multicast frames transmitted.", prometheus.CounterValue},
+	{"brdcstrcv", "receive_broadcast_total", "Broadcast frames received.", prometheus.CounterValue},
+	{"brdcstxmt", "transmit_broadcast_total", "Broadcast frames transmitted.", prometheus.CounterValue},
+}
+
+// kstatVec holds the values of a kstat statistic read at the last scrape,
+// one per set of label values, and exposes them as constant metrics of the
+// statistic value type.
+type kstatVec struct {
+	desc      *prometheus.Desc
+	labels    []string
+	valueType prometheus.ValueType
+
+	mu      sync.Mutex
+	metrics map[string]prometheus.Metric
+}
+
+// newKstatVec returns a newly allocated kstatVec.
+func newKstatVec(name, help string, valueType prometheus.ValueType, labels []string) *kstatVec {
+	return &kstatVec{
+		desc:      prometheus.NewDesc(name, help, labels, nil),
+		labels:    labels,
+		valueType: valueType,
+		metrics:   make(map[string]prometheus.Metric),
+	}
+}
+
+// Describe describes the metrics.
+func (v *kstatVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+// Collect sends the metrics.
+func (v *kstatVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	metrics := make([]prometheus.Metric, 0, len(v.metrics))
+	for _, m := range v.metrics {
+		metrics = append(metrics, m)
+	}
+	v.mu.Unlock()
+
+	for _, m := range metrics {
+		ch <- m
+	}
+}
+
+// Reset deletes all the metrics.
+func (v *kstatVec) Reset() {
+	v.mu.Lock()
+	v.metrics = make(map[string]prometheus.Metric)
+	v.mu.Unlock()
+}
+
+// Set sets the value of the metric with the given labels.
+func (v *kstatVec) Set(labels prometheus.Labels, value float64) {
+	values := make([]string, len(v.labels))
+	for i, l := range v.labels {
+		values[i] = labels[l]
+	}
+	m := prometheus.MustNewConstMetric(v.desc, v.valueType, value, values...)
+
+	v.mu.Lock()
+	v.metrics[strings.Join(values, "\xff")] = m
+	v.mu.Unlock()
+}
+
+// newKstatVecs returns a kstatVec, named after prefix, for each of the
+// statistics.
+func newKstatVecs(stats []kstatStat, prefix string, labels []string) map[string]*kstatVec {
+	vecs := make(map[string]*kstatVec)
+	for _, s := range stats {
+		vecs[s.stat] = newKstatVec(prefix+s.name, s.help, s.valueType, labels)
+	}
+	return vecs
+}
+
+// setKstatVecs sets the kstatVec of each of the statistics found in the
+// kstat entry.
+func setKstatVecs(vecs map[string]*kstatVec, stats []kstatStat, k *kstatEntry, labels prometheus.Labels) error {
+	for _, s := range stats {
+		v, ok := k.stats[s.stat]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		vecs[s.stat].Set(labels, value)
+	}
+	return nil
+}
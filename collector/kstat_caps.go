@@ -0,0 +1,175 @@
+// kstat caps collector
+// this will :
+//  - call kstat on the caps module
+//  - gather per zone and per project CPU cap metrics
+//  - gather per zone resource control metrics
+//  - gather the per zone forks denied by the process and LWP caps
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// cpucapsKstatStats lists the statistics of the cpucaps kstats exposed by the
+// caps collector.
+var cpucapsKstatStats = []kstatStat{
+	{"above_sec", "above_seconds_total", "Time spent above the CPU cap (throttled) in seconds.", prometheus.CounterValue},
+	{"below_sec", "below_seconds_total", "Time spent below the CPU cap in seconds.", prometheus.CounterValue},
+	{"above_base_sec", "above_baseline_seconds_total", "Time spent above the CPU baseline in seconds.", prometheus.CounterValue},
+	{"bursting_sec", "bursting_seconds_total", "Time spent bursting above the CPU baseline in seconds.", prometheus.CounterValue},
+	{"nwait", "waiting_threads", "Number of threads waiting on the CPU cap.", prometheus.GaugeValue},
+}
+
+// capsKstatRctls maps the caps kstats of the zone resource controls to the
+// resource control names.
+var capsKstatRctls = map[string]string{
+	"lwps":   "zone.max-lwps",
+	"msgmni": "zone.max-msg-ids",
+	"nprocs": "zone.max-processes",
+	"semmni": "zone.max-sem-ids",
+	"shmmem": "zone.max-shm-memory",
+	"shmmni": "zone.max-shm-ids",
+}
+
+// capsKstatUnlimited is the value of a resource control without limit.
+const capsKstatUnlimited = "18446744073709551615"
+
+// CapsKstatCollector declares the data type within the prometheus metrics
+// package.
+type CapsKstatCollector struct {
+	CapsKstatForkFail   *kstatVec
+	CapsKstatProjectCPU map[string]*kstatVec
+	CapsKstatRctlLimit  *prometheus.GaugeVec
+	CapsKstatRctlUsage  *prometheus.GaugeVec
+	CapsKstatZoneCPU    map[string]*kstatVec
+}
+
+// NewCapsKstatExporter returns a newly allocated exporter CapsKstatCollector.
+// It exposes how long the zones and projects were throttled by or bursting
+// above their CPU cap, the usage of the zone resource controls, and the forks
+// denied by the zone.max-processes and zone.max-lwps limits.
+func NewCapsKstatExporter() (*CapsKstatCollector, error) {
+	return &CapsKstatCollector{
+		CapsKstatForkFail: newKstatVec("smartos_zone_fork_cap_failures_total",
+			"Forks which failed because the zone reached its process or LWP limit.",
+			prometheus.CounterValue, []string{"zonename"}),
+		CapsKstatProjectCPU: newKstatVecs(cpucapsKstatStats, "smartos_project_cpucap_", []string{"zonename", "project"}),
+		CapsKstatRctlLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_rctl_limit",
+			Help: "The resource control limit; absent when unlimited.",
+		}, []string{"zonename", "rctl"}),
+		CapsKstatRctlUsage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_rctl_usage",
+			Help: "The current usage of the resource control.",
+		}, []string{"zonename", "rctl"}),
+		CapsKstatZoneCPU: newKstatVecs(cpucapsKstatStats, "smartos_zone_cpucap_", []string{"zonename"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *CapsKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range cpucapsKstatStats {
+		e.CapsKstatProjectCPU[s.stat].Describe(ch)
+		e.CapsKstatZoneCPU[s.stat].Describe(ch)
+	}
+	e.CapsKstatForkFail.Describe(ch)
+	e.CapsKstatRctlLimit.Describe(ch)
+	e.CapsKstatRctlUsage.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *CapsKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatCapsList()
+	for _, s := range cpucapsKstatStats {
+		e.CapsKstatProjectCPU[s.stat].Collect(ch)
+		e.CapsKstatZoneCPU[s.stat].Collect(ch)
+	}
+	e.CapsKstatForkFail.Collect(ch)
+	e.CapsKstatRctlLimit.Collect(ch)
+	e.CapsKstatRctlUsage.Collect(ch)
+}
+
+func (e *CapsKstatCollector) kstatCapsList() {
+	// the fork failures are only accounted in the zones kstats
+	out, eerr := exec.Command("kstat", "-p", "caps", "zones:::forkfail_cap", "zones:::zonename").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatCapsListOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing kstat caps list: %v", perr)
+	}
+}
+
+func (e *CapsKstatCollector) parseKstatCapsListOutput(out string) error {
+	// zones and projects come and go, start from a clean state
+	for _, s := range cpucapsKstatStats {
+		e.CapsKstatProjectCPU[s.stat].Reset()
+		e.CapsKstatZoneCPU[s.stat].Reset()
+	}
+	e.CapsKstatForkFail.Reset()
+	e.CapsKstatRctlLimit.Reset()
+	e.CapsKstatRctlUsage.Reset()
+
+	for _, k := range parseKstatOutput(out) {
+		zoneName := k.stats["zonename"]
+		switch {
+		case k.module == "zones":
+			// the kstat name is truncated, the zonename stat is not
+			if v, ok := k.stats["forkfail_cap"]; ok {
+				forkFail, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return err
+				}
+				e.CapsKstatForkFail.Set(prometheus.Labels{"zonename": zoneName}, forkFail)
+			}
+		case strings.HasPrefix(k.name, "cpucaps_zone_"):
+			labels := prometheus.Labels{"zonename": zoneName}
+			if err := setKstatVecs(e.CapsKstatZoneCPU, cpucapsKstatStats, k, labels); err != nil {
+				return err
+			}
+		case strings.HasPrefix(k.name, "cpucaps_project_"):
+			project := strings.TrimPrefix(k.name, "cpucaps_project_")
+			labels := prometheus.Labels{"zonename": zoneName, "project": project}
+			if err := setKstatVecs(e.CapsKstatProjectCPU, cpucapsKstatStats, k, labels); err != nil {
+				return err
+			}
+		case strings.Contains(k.name, "_zone_"):
+			rctl, ok := capsKstatRctls[k.name[:strings.Index(k.name, "_zone_")]]
+			if !ok {
+				continue
+			}
+			if err := e.setCapsKstatRctl(k, prometheus.Labels{"zonename": zoneName, "rctl": rctl}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *CapsKstatCollector) setCapsKstatRctl(k *kstatEntry, labels prometheus.Labels) error {
+	if v, ok := k.stats["usage"]; ok {
+		usage, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		e.CapsKstatRctlUsage.With(labels).Set(usage)
+	}
+
+	if value, ok := k.stats["value"]; ok && value != capsKstatUnlimited {
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		e.CapsKstatRctlLimit.With(labels).Set(limit)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// kstat CPU collector
+// this will :
+//  - call kstat on the cpu sys kstats inside the GZ
+//  - gather per CPU time and scheduler activity metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strconv"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// gzCPUKstatModes maps the CPU time statistics of the cpu sys kstats to the
+// mode label.
+var gzCPUKstatModes = []struct {
+	stat, mode string
+}{
+	{"cpu_nsec_user", "user"},
+	{"cpu_nsec_kernel", "system"},
+	{"cpu_nsec_idle", "idle"},
+	{"cpu_nsec_intr", "irq"},
+	{"cpu_nsec_dtrace", "dtrace"},
+}
+
+// gzCPUKstatStats lists the scheduler statistics of the cpu sys kstats.
+var gzCPUKstatStats = []kstatStat{
+	{"pswitch", "context_switches_total", "Context switches.", prometheus.CounterValue},
+	{"inv_swtch", "involuntary_context_switches_total", "Involuntary context switches.", prometheus.CounterValue},
+	{"cpumigrate", "migrations_total", "Thread migrations to the CPU.", prometheus.CounterValue},
+	{"mutex_adenters", "mutex_spins_total", "Failed adaptive mutex enters (spins).", prometheus.CounterValue},
+	{"rw_rdfails", "rw_read_fails_total", "Failed reader-writer lock read enters.", prometheus.CounterValue},
+	{"intr", "interrupts_total", "Interrupts.", prometheus.CounterValue},
+	{"xcalls", "xcalls_total", "Cross-calls (inter-processor interrupts).", prometheus.CounterValue},
+	{"syscall", "syscalls_total", "System calls.", prometheus.CounterValue},
+}
+
+// GZCPUKstatCollector declares the data type within the prometheus metrics
+// package.
+type GZCPUKstatCollector struct {
+	gzCPUKstat   map[string]*kstatVec
+	gzCPUSeconds *kstatVec
+}
+
+// NewGZCPUKstatExporter returns a newly allocated exporter GZCPUKstatCollector.
+// It exposes the time spent by every CPU in each mode and its scheduler
+// activity, without sampling.
+func NewGZCPUKstatExporter() (*GZCPUKstatCollector, error) {
+	return &GZCPUKstatCollector{
+		gzCPUKstat: newKstatVecs(gzCPUKstatStats, "smartos_cpu_", []string{"cpu"}),
+		gzCPUSeconds: newKstatVec("smartos_cpu_seconds_total",
+			"Seconds the CPUs spent in each mode.",
+			prometheus.CounterValue, []string{"cpu", "mode"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZCPUKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range gzCPUKstatStats {
+		e.gzCPUKstat[s.stat].Describe(ch)
+	}
+	e.gzCPUSeconds.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZCPUKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatCPUList()
+	for _, s := range gzCPUKstatStats {
+		e.gzCPUKstat[s.stat].Collect(ch)
+	}
+	e.gzCPUSeconds.Collect(ch)
+}
+
+func (e *GZCPUKstatCollector) kstatCPUList() {
+	out, eerr := exec.Command("kstat", "-p", "-m", "cpu", "-n", "sys").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatCPUListOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing kstat CPU list: %v", perr)
+	}
+}
+
+func (e *GZCPUKstatCollector) parseKstatCPUListOutput(out string) error {
+	// CPUs can be taken offline or removed
+	for _, vec := range e.gzCPUKstat {
+		vec.Reset()
+	}
+	e.gzCPUSeconds.Reset()
+
+	for _, k := range parseKstatOutput(out) {
+		for _, m := range gzCPUKstatModes {
+			v, ok := k.stats[m.stat]
+			if !ok {
+				continue
+			}
+			nsec, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			e.gzCPUSeconds.Set(prometheus.Labels{"cpu": k.instance, "mode": m.mode}, nsec/1e9)
+		}
+		if err := setKstatVecs(e.gzCPUKstat, gzCPUKstatStats, k, prometheus.Labels{"cpu": k.instance}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
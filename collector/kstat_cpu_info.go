@@ -0,0 +1,188 @@
+// kstat CPU info collector
+// this will :
+//  - call kstat on the cpu_info kstats and psrinfo inside the GZ
+//  - gather CPU topology, frequency and state metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// cpuStates lists the states a CPU can be in.
+var cpuStates = []string{
+	"on-line",
+	"off-line",
+	"no-intr",
+	"spare",
+	"faulted",
+	"powered-off",
+}
+
+// GZCPUInfoCollector declares the data type within the prometheus metrics
+// package.
+type GZCPUInfoCollector struct {
+	gzCPUFrequency    *prometheus.GaugeVec
+	gzCPUFrequencyMax *prometheus.GaugeVec
+	gzCPUFrequencyMin *prometheus.GaugeVec
+	gzCPUInfo         *prometheus.GaugeVec
+	gzCPUState        *prometheus.GaugeVec
+}
+
+// NewGZCPUInfoExporter returns a newly allocated exporter GZCPUInfoCollector.
+// It exposes the topology, the clock frequencies and the state of every CPU.
+func NewGZCPUInfoExporter() (*GZCPUInfoCollector, error) {
+	return &GZCPUInfoCollector{
+		gzCPUFrequency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_frequency_hertz",
+			Help: "Current CPU clock frequency in hertz.",
+		}, []string{"cpu"}),
+		gzCPUFrequencyMax: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_frequency_max_hertz",
+			Help: "Highest supported CPU clock frequency in hertz.",
+		}, []string{"cpu"}),
+		gzCPUFrequencyMin: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_frequency_min_hertz",
+			Help: "Lowest supported CPU clock frequency in hertz.",
+		}, []string{"cpu"}),
+		gzCPUInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_info",
+			Help: "CPU topology and model; always 1.",
+		}, []string{"cpu", "chip_id", "core_id", "brand", "vendor"}),
+		gzCPUState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_state",
+			Help: "CPU state; 1 for the current state, 0 otherwise.",
+		}, []string{"cpu", "state"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZCPUInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzCPUFrequency.Describe(ch)
+	e.gzCPUFrequencyMax.Describe(ch)
+	e.gzCPUFrequencyMin.Describe(ch)
+	e.gzCPUInfo.Describe(ch)
+	e.gzCPUState.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZCPUInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatCPUInfoList()
+	e.psrinfo()
+	e.gzCPUFrequency.Collect(ch)
+	e.gzCPUFrequencyMax.Collect(ch)
+	e.gzCPUFrequencyMin.Collect(ch)
+	e.gzCPUInfo.Collect(ch)
+	e.gzCPUState.Collect(ch)
+}
+
+func (e *GZCPUInfoCollector) kstatCPUInfoList() {
+	out, eerr := exec.Command("kstat", "-p", "-m", "cpu_info").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatCPUInfoListOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing kstat CPU info list: %v", perr)
+	}
+}
+
+func (e *GZCPUInfoCollector) parseKstatCPUInfoListOutput(out string) error {
+	// CPUs can be taken offline or removed
+	e.gzCPUFrequency.Reset()
+	e.gzCPUFrequencyMax.Reset()
+	e.gzCPUFrequencyMin.Reset()
+	e.gzCPUInfo.Reset()
+
+	for _, k := range parseKstatOutput(out) {
+		e.gzCPUInfo.With(prometheus.Labels{
+			"cpu":     k.instance,
+			"chip_id": k.stats["chip_id"],
+			"core_id": k.stats["core_id"],
+			"brand":   k.stats["brand"],
+			"vendor":  k.stats["vendor_id"],
+		}).Set(1)
+
+		labels := prometheus.Labels{"cpu": k.instance}
+		if v, ok := k.stats["current_clock_Hz"]; ok {
+			frequency, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			e.gzCPUFrequency.With(labels).Set(frequency)
+		}
+
+		// e.g. "1200000000:1800000000:2400000000"
+		v, ok := k.stats["supported_frequencies_Hz"]
+		if !ok {
+			continue
+		}
+		var min, max float64
+		for i, f := range strings.Split(v, ":") {
+			frequency, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return err
+			}
+			if i == 0 || frequency < min {
+				min = frequency
+			}
+			if i == 0 || frequency > max {
+				max = frequency
+			}
+		}
+		e.gzCPUFrequencyMin.With(labels).Set(min)
+		e.gzCPUFrequencyMax.With(labels).Set(max)
+	}
+	return nil
+}
+
+func (e *GZCPUInfoCollector) psrinfo() {
+	out, eerr := exec.Command("psrinfo", "-v").Output()
+	if eerr != nil {
+		log.Errorf("error on executing psrinfo: %v", eerr)
+		return
+	}
+	perr := e.parsePsrinfoOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing psrinfo: %v", perr)
+	}
+}
+
+func (e *GZCPUInfoCollector) parsePsrinfoOutput(out string) error {
+	// e.g. "Status of virtual processor 0 as of: 10/19/2026 10:00:00"
+	//      "  on-line since 10/01/2026 08:00:00."
+	rCPU, _ := regexp.Compile(`^Status of virtual processor (\d+) `)
+	rState, _ := regexp.Compile(`^\s+(\S+) since `)
+
+	// CPUs can be taken offline or removed
+	e.gzCPUState.Reset()
+
+	cpu := ""
+	for _, line := range strings.Split(out, "\n") {
+		if fields := rCPU.FindStringSubmatch(line); fields != nil {
+			cpu = fields[1]
+			continue
+		}
+		fields := rState.FindStringSubmatch(line)
+		if fields == nil || cpu == "" {
+			continue
+		}
+		for _, s := range cpuStates {
+			v := 0.0
+			if s == fields[1] {
+				v = 1
+			}
+			e.gzCPUState.With(prometheus.Labels{"cpu": cpu, "state": s}).Set(v)
+		}
+		cpu = ""
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// kstat ether collector
+// this will :
+//  - call kstat and dladm show-phys inside the GZ
+//  - gather Ethernet error metrics of the physical links
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// etherKstatStats lists the statistics of the driver mac kstat exposed by
+// the Ethernet collector.
+var etherKstatStats = []kstatStat{
+	{"fcs_errors", "receive_fcs_errs_total", "Frames received with a bad frame check sequence.", prometheus.CounterValue},
+	{"align_errors", "receive_align_errs_total", "Frames received with an alignment error.", prometheus.CounterValue},
+	{"carrier_errors", "carrier_errs_total", "Times the carrier was lost or never asserted while transmitting.", prometheus.CounterValue},
+	{"jabber_errors", "receive_jabber_errs_total", "Oversize frames received with a bad frame check sequence.", prometheus.CounterValue},
+	{"toolong_errors", "receive_oversize_errs_total", "Frames received exceeding the maximum frame size.", prometheus.CounterValue},
+	{"runt_errors", "receive_undersize_errs_total", "Frames received shorter than the minimum frame size.", prometheus.CounterValue},
+	{"norcvbuf", "receive_drop_total", "Received frames dropped for lack of buffers.", prometheus.CounterValue},
+	{"noxmtbuf", "transmit_drop_total", "Frames not transmitted for lack of buffers.", prometheus.CounterValue},
+}
+
+// GZEtherKstatCollector declares the data type within the prometheus metrics
+// package.
+type GZEtherKstatCollector struct {
+	gzEtherKstat map[string]*kstatVec
+}
+
+// NewGZEtherKstatExporter returns a newly allocated exporter GZEtherKstatCollector.
+// It exposes the Ethernet errors of the physical links, as reported by their
+// driver.
+func NewGZEtherKstatExporter() (*GZEtherKstatCollector, error) {
+	return &GZEtherKstatCollector{
+		gzEtherKstat: newKstatVecs(etherKstatStats, "smartos_network_ether_", []string{"link", "driver"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZEtherKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range etherKstatStats {
+		e.gzEtherKstat[s.stat].Describe(ch)
+	}
+}
+
+// Collect fetches the stats.
+func (e *GZEtherKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatEtherList()
+	for _, s := range etherKstatStats {
+		e.gzEtherKstat[s.stat].Collect(ch)
+	}
+}
+
+func (e *GZEtherKstatCollector) kstatEtherList() {
+	out, eerr := exec.Command("dladm", "show-phys", "-p", "-o", "link,device").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	links := parseDladmShowPhysDevicesOutput(string(out))
+
+	out, eerr = exec.Command("kstat", "-p", "-c", "net", "-n", "mac").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatEtherListOutput(string(out), links)
+	if perr != nil {
+		log.Errorf("error on parsing kstat ether list: %v", perr)
+	}
+}
+
+func (e *GZEtherKstatCollector) parseKstatEtherListOutput(out string, links map[string]string) error {
+	// physical links can be removed or renamed
+	for _, vec := range e.gzEtherKstat {
+		vec.Reset()
+	}
+
+	for _, k := range parseKstatOutput(out) {
+		// the driver kstat is named after the driver and its instance
+		// (e.g. ixgbe:0:mac for ixgbe0)
+		link, ok := links[k.module+k.instance]
+		if !ok {
+			continue
+		}
+		labels := prometheus.Labels{"link": link, "driver": k.module}
+		if err := setKstatVecs(e.gzEtherKstat, etherKstatStats, k, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDladmShowPhysDevicesOutput maps each device of
+// dladm show-phys -p -o link,device to its link.
+func parseDladmShowPhysDevicesOutput(out string) map[string]string {
+	links := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 2 {
+			continue
+		}
+		links[parsedLine[1]] = parsedLine[0]
+	}
+	return links
+}
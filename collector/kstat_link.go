@@ -0,0 +1,106 @@
+// kstat link collector
+// this will :
+//  - call kstat and dladm show-link inside the GZ
+//  - gather network metrics of the aggregations, physical links and etherstubs
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// gzLinkKstatClasses lists the dladm link classes owned by the GZ.
+var gzLinkKstatClasses = map[string]bool{
+	"aggr":      true,
+	"etherstub": true,
+	"phys":      true,
+}
+
+// GZLinkKstatCollector declares the data type within the prometheus metrics
+// package.
+type GZLinkKstatCollector struct {
+	gzLinkKstat map[string]*kstatVec
+}
+
+// NewGZLinkKstatExporter returns a newly allocated exporter GZLinkKstatCollector.
+// It exposes the network counters of the GZ links.
+func NewGZLinkKstatExporter() (*GZLinkKstatCollector, error) {
+	return &GZLinkKstatCollector{
+		gzLinkKstat: newKstatVecs(linkKstatStats, "smartos_network_link_", []string{"device", "class"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZLinkKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range linkKstatStats {
+		e.gzLinkKstat[s.stat].Describe(ch)
+	}
+}
+
+// Collect fetches the stats.
+func (e *GZLinkKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatLinkList()
+	for _, s := range linkKstatStats {
+		e.gzLinkKstat[s.stat].Collect(ch)
+	}
+}
+
+func (e *GZLinkKstatCollector) kstatLinkList() {
+	out, eerr := exec.Command("dladm", "show-link", "-p", "-o", "link,class").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	classes := parseDladmShowLinkOutput(string(out))
+
+	out, eerr = exec.Command("kstat", "-p", "-m", "link").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatLinkListOutput(string(out), classes)
+	if perr != nil {
+		log.Errorf("error on parsing kstat link list: %v", perr)
+	}
+}
+
+func (e *GZLinkKstatCollector) parseKstatLinkListOutput(out string, classes map[string]string) error {
+	// aggregations and etherstubs come and go
+	for _, vec := range e.gzLinkKstat {
+		vec.Reset()
+	}
+
+	for _, k := range parseKstatOutput(out) {
+		// zone VNICs are exposed to the GZ as well
+		if zoneName, ok := k.stats["zonename"]; ok && zoneName != "global" {
+			continue
+		}
+		class := classes[k.name]
+		if !gzLinkKstatClasses[class] {
+			continue
+		}
+		if err := setKstatVecs(e.gzLinkKstat, linkKstatStats, k, prometheus.Labels{"device": k.name, "class": class}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDladmShowLinkOutput maps each link of dladm show-link -p -o link,class
+// to its class.
+func parseDladmShowLinkOutput(out string) map[string]string {
+	classes := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 2 {
+			continue
+		}
+		classes[parsedLine[0]] = parsedLine[1]
+	}
+	return classes
+}
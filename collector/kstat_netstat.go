@@ -0,0 +1,156 @@
+// kstat netstat collector
+// this will :
+//  - call kstat on the ip, icmp, tcp and udp MIB
+//  - gather TCP/IP protocol metrics of every IP stack
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// netstatKstatProtocols lists the MIB kstats (module:instance:name) read by
+// the netstat collector.
+var netstatKstatProtocols = []string{"ip::ip", "ip::icmp", "tcp::tcp", "udp::udp"}
+
+// netstatKstatStats maps the MIB kstat names to the statistics exposed.
+var netstatKstatStats = map[string][]kstatStat{
+	"ip": {
+		{"inReceives", "in_receives_total", "IP datagrams received.", prometheus.CounterValue},
+		{"inHdrErrors", "in_hdr_errs_total", "IP datagrams discarded for a header error.", prometheus.CounterValue},
+		{"inAddrErrors", "in_addr_errs_total", "IP datagrams discarded for an invalid destination address.", prometheus.CounterValue},
+		{"inDiscards", "in_discards_total", "IP datagrams received and discarded.", prometheus.CounterValue},
+		{"inDelivers", "in_delivers_total", "IP datagrams delivered to the upper layers.", prometheus.CounterValue},
+		{"outRequests", "out_requests_total", "IP datagrams handed down for transmission.", prometheus.CounterValue},
+		{"outDiscards", "out_discards_total", "IP datagrams discarded before transmission.", prometheus.CounterValue},
+		{"outNoRoutes", "out_no_routes_total", "IP datagrams discarded because no route could be found.", prometheus.CounterValue},
+		{"forwDatagrams", "forw_datagrams_total", "IP datagrams forwarded.", prometheus.CounterValue},
+		{"reasmFails", "reasm_fails_total", "IP reassembly failures.", prometheus.CounterValue},
+		{"fragFails", "frag_fails_total", "IP datagrams which could not be fragmented.", prometheus.CounterValue},
+		{"udpNoPorts", "udp_no_ports_total", "UDP datagrams received for a port without listener.", prometheus.CounterValue},
+		{"udpInOverflows", "udp_in_overflows_total", "UDP datagrams dropped on a full receive queue.", prometheus.CounterValue},
+	},
+	"icmp": {
+		{"inMsgs", "in_msgs_total", "ICMP messages received.", prometheus.CounterValue},
+		{"inErrors", "in_errs_total", "ICMP messages received with an error.", prometheus.CounterValue},
+		{"inDestUnreachs", "in_dest_unreachs_total", "ICMP destination unreachable messages received.", prometheus.CounterValue},
+		{"inEchos", "in_echos_total", "ICMP echo requests received.", prometheus.CounterValue},
+		{"outMsgs", "out_msgs_total", "ICMP messages sent.", prometheus.CounterValue},
+		{"outErrors", "out_errs_total", "ICMP messages not sent due to an error.", prometheus.CounterValue},
+		{"outDestUnreachs", "out_dest_unreachs_total", "ICMP destination unreachable messages sent.", prometheus.CounterValue},
+		{"outEchoReps", "out_echo_reps_total", "ICMP echo replies sent.", prometheus.CounterValue},
+	},
+	"tcp": {
+		{"activeOpens", "active_opens_total", "TCP connections actively opened.", prometheus.CounterValue},
+		{"passiveOpens", "passive_opens_total", "TCP connections passively opened.", prometheus.CounterValue},
+		{"attemptFails", "attempt_fails_total", "TCP connection attempts which failed.", prometheus.CounterValue},
+		{"estabResets", "estab_resets_total", "TCP established connections reset.", prometheus.CounterValue},
+		{"currEstab", "curr_estab", "TCP connections currently established or in CLOSE_WAIT.", prometheus.GaugeValue},
+		{"inSegs", "in_segs_total", "TCP segments received.", prometheus.CounterValue},
+		{"outSegs", "out_segs_total", "TCP segments sent.", prometheus.CounterValue},
+		{"retransSegs", "retrans_segs_total", "TCP segments retransmitted.", prometheus.CounterValue},
+		{"outRsts", "out_rsts_total", "TCP segments sent with the RST flag.", prometheus.CounterValue},
+		{"inErrs", "in_errs_total", "TCP segments received with an error.", prometheus.CounterValue},
+		{"listenDrop", "listen_drop_total", "TCP connections dropped on a full listen queue.", prometheus.CounterValue},
+		{"listenDropQ0", "listen_drop_q0_total", "TCP connections dropped on a full incomplete connection queue.", prometheus.CounterValue},
+		{"halfOpenDrop", "half_open_drop_total", "TCP half open connections dropped.", prometheus.CounterValue},
+		{"timRetransDrop", "tim_retrans_drop_total", "TCP connections dropped after too many retransmissions.", prometheus.CounterValue},
+	},
+	"udp": {
+		{"inDatagrams", "in_datagrams_total", "UDP datagrams received.", prometheus.CounterValue},
+		{"inErrors", "in_errs_total", "UDP datagrams received with an error.", prometheus.CounterValue},
+		{"outDatagrams", "out_datagrams_total", "UDP datagrams sent.", prometheus.CounterValue},
+		{"outErrors", "out_errs_total", "UDP datagrams not sent due to an error.", prometheus.CounterValue},
+	},
+}
+
+// NetstatKstatCollector declares the data type within the prometheus metrics
+// package.
+type NetstatKstatCollector struct {
+	netstatKstat map[string]map[string]*kstatVec
+}
+
+// NewNetstatKstatExporter returns a newly allocated exporter NetstatKstatCollector.
+// It exposes the TCP/IP protocol statistics of each IP stack.
+func NewNetstatKstatExporter() (*NetstatKstatCollector, error) {
+	vecs := make(map[string]map[string]*kstatVec)
+	for name, stats := range netstatKstatStats {
+		vecs[name] = newKstatVecs(stats, "smartos_netstat_"+name+"_", []string{"zonename"})
+	}
+	return &NetstatKstatCollector{
+		netstatKstat: vecs,
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *NetstatKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for name, stats := range netstatKstatStats {
+		for _, s := range stats {
+			e.netstatKstat[name][s.stat].Describe(ch)
+		}
+	}
+}
+
+// Collect fetches the stats.
+func (e *NetstatKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatNetstatList()
+	for name, stats := range netstatKstatStats {
+		for _, s := range stats {
+			e.netstatKstat[name][s.stat].Collect(ch)
+		}
+	}
+}
+
+func (e *NetstatKstatCollector) kstatNetstatList() {
+	out, eerr := exec.Command("zoneadm", "list", "-p").Output()
+	if eerr != nil {
+		log.Errorf("error on executing zoneadm: %v", eerr)
+		return
+	}
+	zoneNames := make(map[string]string)
+	for _, z := range parseZoneadmListOutput(string(out)) {
+		zoneNames[z.id] = z.name
+	}
+
+	out, eerr = exec.Command("kstat", append([]string{"-p"}, netstatKstatProtocols...)...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatNetstatListOutput(string(out), zoneNames)
+	if perr != nil {
+		log.Errorf("error on parsing kstat netstat list: %v", perr)
+	}
+}
+
+func (e *NetstatKstatCollector) parseKstatNetstatListOutput(out string, zoneNames map[string]string) error {
+	// exclusive IP stacks come and go with the zones, and zone IDs are
+	// reused
+	for _, vecs := range e.netstatKstat {
+		for _, vec := range vecs {
+			vec.Reset()
+		}
+	}
+
+	for _, k := range parseKstatOutput(out) {
+		stats, ok := netstatKstatStats[k.name]
+		if !ok {
+			continue
+		}
+		// the instance is the IP stack, shared IP zones use the one of
+		// the GZ (0), exclusive IP zones their own (their zone ID);
+		// skip the stacks of the zones halted since zoneadm was run
+		zoneName, ok := zoneNames[k.instance]
+		if !ok {
+			continue
+		}
+		if err := setKstatVecs(e.netstatKstat[k.name], stats, k, prometheus.Labels{"zonename": zoneName}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"testing"
+)
+
+// kstatLinkOutput is a kstat -p -m link output of the GZ, with a GZ link and
+// two zone VNICs sharing the same name.
+const kstatLinkOutput = `link:0:aggr0:class	net
+link:0:aggr0:crtime	64.116279381
+link:0:aggr0:ipackets64	2094612
+link:0:aggr0:obytes64	1210834
+link:0:aggr0:rbytes64	185347601
+link:0:aggr0:snaptime	86400.123456789
+link:0:aggr0:zonename	global
+link:0:net0:class	net
+link:0:net0:ipackets64	5120
+link:0:net0:obytes64	40960
+link:0:net0:rbytes64	81920
+link:0:net0:zonename	b2c6f7e4-2b1c-4a5e-9f3e-0123456789ab
+link:0:net0:brdcstrcv	3
+link:0:net0:class	net
+link:0:net0:ipackets64	1024
+link:0:net0:obytes64	2048
+link:0:net0:rbytes64	4096
+link:0:net0:zonename	c8d1e5a2-7f3b-4c6d-8e9f-fedcba987654
+`
+
+func TestParseKstatOutput(t *testing.T) {
+	entries := parseKstatOutput(kstatLinkOutput)
+	if len(entries) != 3 {
+		t.Fatalf("got %d kstats, want 3", len(entries))
+	}
+
+	want := []struct {
+		name, zoneName, rbytes64 string
+		stats                    int
+	}{
+		{"aggr0", "global", "185347601", 7},
+		{"net0", "b2c6f7e4-2b1c-4a5e-9f3e-0123456789ab", "81920", 5},
+		{"net0", "c8d1e5a2-7f3b-4c6d-8e9f-fedcba987654", "4096", 6},
+	}
+	for i, w := range want {
+		k := entries[i]
+		if k.module != "link" || k.instance != "0" || k.name != w.name {
+			t.Errorf("kstat %d: got %s:%s:%s, want link:0:%s", i, k.module, k.instance, k.name, w.name)
+		}
+		if got := k.stats["zonename"]; got != w.zoneName {
+			t.Errorf("kstat %d: got zonename %q, want %q", i, got, w.zoneName)
+		}
+		if got := k.stats["rbytes64"]; got != w.rbytes64 {
+			t.Errorf("kstat %d: got rbytes64 %q, want %q", i, got, w.rbytes64)
+		}
+		if got := len(k.stats); got != w.stats {
+			t.Errorf("kstat %d: got %d statistics, want %d", i, got, w.stats)
+		}
+	}
+}
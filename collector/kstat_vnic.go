@@ -0,0 +1,163 @@
+// kstat VNIC collector
+// this will :
+//  - call kstat, dladm show-vnic and vmadm inside the GZ
+//  - gather network metrics of every zone VNIC
+//  - feed the collector
+
+package collector
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// GZVNICKstatCollector declares the data type within the prometheus metrics
+// package.
+type GZVNICKstatCollector struct {
+	gzVNICKstat map[string]*kstatVec
+}
+
+// GZVNICKey defines the mapping of a zone VNIC name.
+type GZVNICKey struct {
+	zoneName, link string
+}
+
+// GZVNIC defines the mapping of the dladm and vmadm properties of a VNIC.
+type GZVNIC struct {
+	over, vid, mac, nicTag string
+}
+
+// gzVMNICs defines the mapping of vmadm lookup -j -o uuid,nics.
+type gzVMNICs struct {
+	UUID string `json:"uuid"`
+	NICs []struct {
+		Interface string `json:"interface"`
+		NICTag    string `json:"nic_tag"`
+	} `json:"nics"`
+}
+
+// NewGZVNICKstatExporter returns a newly allocated exporter GZVNICKstatCollector.
+// It exposes the network counters of the VNICs of every zone.
+func NewGZVNICKstatExporter() (*GZVNICKstatCollector, error) {
+	return &GZVNICKstatCollector{
+		gzVNICKstat: newKstatVecs(linkKstatStats, "smartos_network_vnic_",
+			[]string{"zonename", "device", "over", "vlan_id", "mac", "nic_tag"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZVNICKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range linkKstatStats {
+		e.gzVNICKstat[s.stat].Describe(ch)
+	}
+}
+
+// Collect fetches the stats.
+func (e *GZVNICKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatVNICList()
+	for _, s := range linkKstatStats {
+		e.gzVNICKstat[s.stat].Collect(ch)
+	}
+}
+
+func (e *GZVNICKstatCollector) kstatVNICList() {
+	out, eerr := exec.Command("dladm", "show-vnic", "-p", "-o", "link,over,vid,macaddress,zone").Output()
+	if eerr != nil {
+		log.Errorf("error on executing dladm: %v", eerr)
+		return
+	}
+	vnics := parseDladmShowVNICOutput(string(out))
+
+	// NIC tags are only known by vmadm, go on without them otherwise
+	out, eerr = exec.Command("vmadm", "lookup", "-j", "-o", "uuid,nics").Output()
+	if eerr != nil {
+		log.Errorf("error on executing vmadm: %v", eerr)
+	} else if perr := parseVmadmNICsOutput(string(out), vnics); perr != nil {
+		log.Errorf("error on parsing vmadm lookup: %v", perr)
+	}
+
+	out, eerr = exec.Command("kstat", "-p", "-m", "link").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatVNICListOutput(string(out), vnics)
+	if perr != nil {
+		log.Errorf("error on parsing kstat VNIC list: %v", perr)
+	}
+}
+
+func (e *GZVNICKstatCollector) parseKstatVNICListOutput(out string, vnics map[GZVNICKey]*GZVNIC) error {
+	// VNICs come and go with the zones
+	for _, vec := range e.gzVNICKstat {
+		vec.Reset()
+	}
+
+	for _, k := range parseKstatOutput(out) {
+		zoneName := k.stats["zonename"]
+		if zoneName == "" || zoneName == "global" {
+			continue
+		}
+		vnic, ok := vnics[GZVNICKey{zoneName, k.name}]
+		if !ok {
+			vnic = &GZVNIC{}
+		}
+		labels := prometheus.Labels{
+			"zonename": zoneName,
+			"device":   k.name,
+			"over":     vnic.over,
+			"vlan_id":  vnic.vid,
+			"mac":      vnic.mac,
+			"nic_tag":  vnic.nicTag,
+		}
+		if err := setKstatVecs(e.gzVNICKstat, linkKstatStats, k, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDladmShowVNICOutput maps the zone VNICs of
+// dladm show-vnic -p -o link,over,vid,macaddress,zone by zone and link name.
+func parseDladmShowVNICOutput(out string) map[GZVNICKey]*GZVNIC {
+	vnics := make(map[GZVNICKey]*GZVNIC)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := parseDladmLine(line)
+		if len(parsedLine) != 5 {
+			continue
+		}
+		// links assigned to a zone may be prefixed by the zone name
+		link := parsedLine[0]
+		if i := strings.LastIndex(link, "/"); i >= 0 {
+			link = link[i+1:]
+		}
+		zoneName := parsedLine[4]
+		vnics[GZVNICKey{zoneName, link}] = &GZVNIC{
+			over: parsedLine[1],
+			vid:  parsedLine[2],
+			mac:  parsedLine[3],
+		}
+	}
+	return vnics
+}
+
+// parseVmadmNICsOutput sets the NIC tag of the known zone VNICs from
+// vmadm lookup -j -o uuid,nics.
+func parseVmadmNICsOutput(out string, vnics map[GZVNICKey]*GZVNIC) error {
+	var vms []gzVMNICs
+	if err := json.Unmarshal([]byte(out), &vms); err != nil {
+		return err
+	}
+	for _, vm := range vms {
+		for _, nic := range vm.NICs {
+			if vnic, ok := vnics[GZVNICKey{vm.UUID, nic.Interface}]; ok {
+				vnic.nicTag = nic.NICTag
+			}
+		}
+	}
+	return nil
+}
@@ -8,45 +8,49 @@ package collector
 
 import (
 	"os/exec"
-	"regexp"
 	"strconv"
-	"strings"
 	// Prometheus Go toolset
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 )
 
-// ZoneKstatCollector declares the data type within the prometheus metrics package.
-type ZoneKstatCollector struct {
-	ZoneKstatCPUBaseline   *prometheus.GaugeVec
-	ZoneKstatCPUCap        *prometheus.GaugeVec
-	ZoneKstatCPUMaxUsage   *prometheus.GaugeVec
-	ZoneKstatCPUUsage      *prometheus.GaugeVec
-	ZoneKstatMemCap        *prometheus.GaugeVec
-	ZoneKstatMemFree       *prometheus.GaugeVec
-	ZoneKstatMemNover      *prometheus.GaugeVec
-	ZoneKstatMemPagedOut   *prometheus.GaugeVec
-	ZoneKstatMemRSS        *prometheus.GaugeVec
-	ZoneKstatNICCollisions *prometheus.GaugeVec
-	ZoneKstatNICIErrors    *prometheus.GaugeVec
-	ZoneKstatNICIPackets   *prometheus.GaugeVec
-	ZoneKstatNICLinkState  *prometheus.GaugeVec
-	ZoneKstatNICOBytes     *prometheus.GaugeVec
-	ZoneKstatNICOErrors    *prometheus.GaugeVec
-	ZoneKstatNICOPackets   *prometheus.GaugeVec
-	ZoneKstatNICRBytes     *prometheus.GaugeVec
-	ZoneKstatSwapCap       *prometheus.GaugeVec
-	ZoneKstatSwapFree      *prometheus.GaugeVec
-	ZoneKstatSwapUsed      *prometheus.GaugeVec
+// memoryCapKstatStats lists the over cap, allocation failure and paging
+// statistics of the memory_cap kstat.
+var memoryCapKstatStats = []kstatStat{
+	{"nover", "nover_total", "The number of times the zone has gone over its cap.", prometheus.CounterValue},
+	{"pagedout", "pagedout_bytes", "Total amount of memory that has been paged out when the zone has gone over its cap.", prometheus.CounterValue},
+	{"anon_alloc_fail", "anon_alloc_fail_total", "The number of anonymous memory allocations that failed because of the cap.", prometheus.CounterValue},
+	{"anonpgin", "anon_pagein_total", "The number of anonymous pages paged in.", prometheus.CounterValue},
+	{"execpgin", "exec_pagein_total", "The number of executable pages paged in.", prometheus.CounterValue},
+	{"fspgin", "fs_pagein_total", "The number of file system pages paged in.", prometheus.CounterValue},
+	{"pgpgin", "pagein_total", "The number of pages paged in.", prometheus.CounterValue},
 }
 
-// ZoneKstatNIC defines the mapping of kstat link structure.
-type ZoneKstatNIC struct {
-	ifName, ifLabel string
-}
+// zoneNICKstatStats lists the link statistics exposed by the zone NIC
+// collector; the shared link statistics plus the state and speed of the link.
+var zoneNICKstatStats = append([]kstatStat{
+	{"unknowns", "receive_unknowns_total", "Frames received with an unknown protocol.", prometheus.CounterValue},
+	{"collisions", "collisions", "Entire amount of collisions.", prometheus.CounterValue},
+	{"link_state", "link_state", "Link state; 0 for down, 1 for up.", prometheus.GaugeValue},
+	{"link_duplex", "link_duplex", "Link duplex; 0 for unknown, 1 for half, 2 for full.", prometheus.GaugeValue},
+	{"ifspeed", "speed_bits", "Link speed in bits per second.", prometheus.GaugeValue},
+}, linkKstatStats...)
 
-// ZoneKstatNICs slice for key iteration
-type ZoneKstatNICs []ZoneKstatNIC
+// ZoneKstatCollector declares the data type within the prometheus metrics package.
+type ZoneKstatCollector struct {
+	ZoneKstatCPUBaseline *prometheus.GaugeVec
+	ZoneKstatCPUCap      *prometheus.GaugeVec
+	ZoneKstatCPUMaxUsage *prometheus.GaugeVec
+	ZoneKstatCPUUsage    *prometheus.GaugeVec
+	ZoneKstatMemCap      *prometheus.GaugeVec
+	ZoneKstatMemFree     *prometheus.GaugeVec
+	ZoneKstatMemPaging   map[string]*kstatVec
+	ZoneKstatMemRSS      *prometheus.GaugeVec
+	ZoneKstatNIC         map[string]*kstatVec
+	ZoneKstatSwapCap     *prometheus.GaugeVec
+	ZoneKstatSwapFree    *prometheus.GaugeVec
+	ZoneKstatSwapUsed    *prometheus.GaugeVec
+}
 
 // NewZoneKstatExporter returns a newly allocated exporter ZoneKstatCollector.
 // It exposes the kstat command result.
@@ -76,50 +80,12 @@ func NewZoneKstatExporter() (*ZoneKstatCollector, error) {
 			Name: "smartos_memory_free_bytes",
 			Help: "Free memory available in bytes.",
 		}, []string{"zonename"}),
-		ZoneKstatMemNover: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_memory_nover_total",
-			Help: "The number of times the zone has gone over its cap.",
-		}, []string{"zonename"}),
-		ZoneKstatMemPagedOut: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_memory_pagedout_bytes",
-			Help: "Total amount of memory that has been paged out when the zone has gone over its cap.",
-		}, []string{"zonename"}),
+		ZoneKstatMemPaging: newKstatVecs(memoryCapKstatStats, "smartos_memory_", []string{"zonename"}),
 		ZoneKstatMemRSS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "smartos_memory_rss_bytes",
 			Help: "Entire amount of allocated memory.",
 		}, []string{"zonename"}),
-		ZoneKstatNICCollisions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_collisions",
-			Help: "Entire amount of collisions.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICIErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_receive_errs_total",
-			Help: "Received errors.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICIPackets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_receive_packets_total",
-			Help: "Frames received successfully.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICLinkState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_link_state",
-			Help: "Link state; 0 for down, 1 for up.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICOBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_transmit_bytes_total",
-			Help: "Bytes (octets) transmitted successfully.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICOErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_transmit_errs_total",
-			Help: "Transmit errors.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICOPackets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_transmit_packets_total",
-			Help: "Frames successfully transmitted.",
-		}, []string{"zonename", "device"}),
-		ZoneKstatNICRBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "smartos_network_receive_bytes_total",
-			Help: "Bytes (octets) received successfully.",
-		}, []string{"zonename", "device"}),
+		ZoneKstatNIC: newKstatVecs(zoneNICKstatStats, "smartos_network_", []string{"zonename", "device"}),
 		ZoneKstatSwapCap: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "smartos_memory_swap_cap_bytes",
 			Help: "The SWAP limit in bytes.",
@@ -143,17 +109,13 @@ func (e *ZoneKstatCollector) Describe(ch chan<- *prometheus.Desc) {
 	e.ZoneKstatCPUUsage.Describe(ch)
 	e.ZoneKstatMemCap.Describe(ch)
 	e.ZoneKstatMemFree.Describe(ch)
-	e.ZoneKstatMemNover.Describe(ch)
-	e.ZoneKstatMemPagedOut.Describe(ch)
+	for _, s := range memoryCapKstatStats {
+		e.ZoneKstatMemPaging[s.stat].Describe(ch)
+	}
 	e.ZoneKstatMemRSS.Describe(ch)
-	e.ZoneKstatNICCollisions.Describe(ch)
-	e.ZoneKstatNICIErrors.Describe(ch)
-	e.ZoneKstatNICIPackets.Describe(ch)
-	e.ZoneKstatNICLinkState.Describe(ch)
-	e.ZoneKstatNICOBytes.Describe(ch)
-	e.ZoneKstatNICOErrors.Describe(ch)
-	e.ZoneKstatNICOPackets.Describe(ch)
-	e.ZoneKstatNICRBytes.Describe(ch)
+	for _, s := range zoneNICKstatStats {
+		e.ZoneKstatNIC[s.stat].Describe(ch)
+	}
 	e.ZoneKstatSwapCap.Describe(ch)
 	e.ZoneKstatSwapFree.Describe(ch)
 	e.ZoneKstatSwapUsed.Describe(ch)
@@ -170,17 +132,13 @@ func (e *ZoneKstatCollector) Collect(ch chan<- prometheus.Metric) {
 	e.ZoneKstatCPUUsage.Collect(ch)
 	e.ZoneKstatMemCap.Collect(ch)
 	e.ZoneKstatMemFree.Collect(ch)
-	e.ZoneKstatMemNover.Collect(ch)
-	e.ZoneKstatMemPagedOut.Collect(ch)
+	for _, s := range memoryCapKstatStats {
+		e.ZoneKstatMemPaging[s.stat].Collect(ch)
+	}
 	e.ZoneKstatMemRSS.Collect(ch)
-	e.ZoneKstatNICCollisions.Collect(ch)
-	e.ZoneKstatNICIErrors.Collect(ch)
-	e.ZoneKstatNICIPackets.Collect(ch)
-	e.ZoneKstatNICLinkState.Collect(ch)
-	e.ZoneKstatNICOBytes.Collect(ch)
-	e.ZoneKstatNICOErrors.Collect(ch)
-	e.ZoneKstatNICOPackets.Collect(ch)
-	e.ZoneKstatNICRBytes.Collect(ch)
+	for _, s := range zoneNICKstatStats {
+		e.ZoneKstatNIC[s.stat].Collect(ch)
+	}
 	e.ZoneKstatSwapCap.Collect(ch)
 	e.ZoneKstatSwapFree.Collect(ch)
 	e.ZoneKstatSwapUsed.Collect(ch)
@@ -220,191 +178,77 @@ func (e *ZoneKstatCollector) kstatNICList() {
 }
 
 func (e *ZoneKstatCollector) parseKstatCPUListOutput(out string) error {
-	// trim the label in order to obtain the type of metric
-	r, _ := regexp.Compile(`(?m)^.*:.*:.*:`)
-
-	outlines := strings.Split(out, "\n")
-	l := len(outlines)
-	m := make(map[string]string)
-	for _, line := range outlines[1 : l-1] {
-		parsedLine := strings.Fields(line)
-		fullLabel := parsedLine[0]
-		label := r.ReplaceAllString(fullLabel, "")
-		// map label and values
-		m[label] = parsedLine[1]
-	}
+	for _, k := range parseKstatOutput(out) {
+		baseline, err := strconv.ParseFloat(k.stats["baseline"], 64)
+		if err != nil {
+			return err
+		}
+		cap, err := strconv.ParseFloat(k.stats["value"], 64)
+		if err != nil {
+			return err
+		}
+		maxUsage, err := strconv.ParseFloat(k.stats["maxusage"], 64)
+		if err != nil {
+			return err
+		}
+		usage, err := strconv.ParseFloat(k.stats["usage"], 64)
+		if err != nil {
+			return err
+		}
 
-	baseline, err := strconv.ParseFloat(m["baseline"], 64)
-	if err != nil {
-		return err
-	}
-	cap, err := strconv.ParseFloat(m["value"], 64)
-	if err != nil {
-		return err
-	}
-	maxUsage, err := strconv.ParseFloat(m["maxusage"], 64)
-	if err != nil {
-		return err
+		labels := prometheus.Labels{"zonename": k.stats["zonename"]}
+		e.ZoneKstatCPUBaseline.With(labels).Set(baseline)
+		e.ZoneKstatCPUCap.With(labels).Set(cap)
+		e.ZoneKstatCPUMaxUsage.With(labels).Set(maxUsage)
+		e.ZoneKstatCPUUsage.With(labels).Set(usage)
 	}
-	usage, err := strconv.ParseFloat(m["usage"], 64)
-	if err != nil {
-		return err
-	}
-
-	e.ZoneKstatCPUBaseline.With(prometheus.Labels{"zonename": m["zonename"]}).Set(baseline)
-	e.ZoneKstatCPUCap.With(prometheus.Labels{"zonename": m["zonename"]}).Set(cap)
-	e.ZoneKstatCPUMaxUsage.With(prometheus.Labels{"zonename": m["zonename"]}).Set(maxUsage)
-	e.ZoneKstatCPUUsage.With(prometheus.Labels{"zonename": m["zonename"]}).Set(usage)
-
 	return nil
 }
 
 func (e *ZoneKstatCollector) parseKstatMemListOutput(out string) error {
-	// trim the label in order to obtain the type of metric
-	r, _ := regexp.Compile(`(?m)^.*:.*:.*:`)
-
-	outlines := strings.Split(out, "\n")
-	l := len(outlines)
-	m := make(map[string]string)
-	for _, line := range outlines[1 : l-1] {
-		parsedLine := strings.Fields(line)
-		fullLabel := parsedLine[0]
-		label := r.ReplaceAllString(fullLabel, "")
-		// map label and values
-		m[label] = parsedLine[1]
-	}
-
-	memCap, err := strconv.ParseFloat(m["physcap"], 64)
-	if err != nil {
-		return err
-	}
-	memNover, err := strconv.ParseFloat(m["nover"], 64)
-	if err != nil {
-		return err
-	}
-	memPagedOut, err := strconv.ParseFloat(m["pagedout"], 64)
-	if err != nil {
-		return err
-	}
-	memRSS, err := strconv.ParseFloat(m["rss"], 64)
-	if err != nil {
-		return err
-	}
-	memFree := memCap - memRSS
+	for _, k := range parseKstatOutput(out) {
+		memCap, err := strconv.ParseFloat(k.stats["physcap"], 64)
+		if err != nil {
+			return err
+		}
+		memRSS, err := strconv.ParseFloat(k.stats["rss"], 64)
+		if err != nil {
+			return err
+		}
+		memFree := memCap - memRSS
 
-	swapCap, err := strconv.ParseFloat(m["swapcap"], 64)
-	if err != nil {
-		return err
-	}
-	swapUsed, err := strconv.ParseFloat(m["swap"], 64)
-	if err != nil {
-		return err
-	}
-	swapFree := memCap - memRSS
+		swapCap, err := strconv.ParseFloat(k.stats["swapcap"], 64)
+		if err != nil {
+			return err
+		}
+		swapUsed, err := strconv.ParseFloat(k.stats["swap"], 64)
+		if err != nil {
+			return err
+		}
+		swapFree := swapCap - swapUsed
 
-	e.ZoneKstatMemCap.With(prometheus.Labels{"zonename": m["zonename"]}).Set(memCap)
-	e.ZoneKstatMemFree.With(prometheus.Labels{"zonename": m["zonename"]}).Set(memFree)
-	e.ZoneKstatMemNover.With(prometheus.Labels{"zonename": m["zonename"]}).Set(memNover)
-	e.ZoneKstatMemPagedOut.With(prometheus.Labels{"zonename": m["zonename"]}).Set(memPagedOut)
-	e.ZoneKstatMemRSS.With(prometheus.Labels{"zonename": m["zonename"]}).Set(memRSS)
+		labels := prometheus.Labels{"zonename": k.stats["zonename"]}
+		e.ZoneKstatMemCap.With(labels).Set(memCap)
+		e.ZoneKstatMemFree.With(labels).Set(memFree)
+		e.ZoneKstatMemRSS.With(labels).Set(memRSS)
 
-	e.ZoneKstatSwapCap.With(prometheus.Labels{"zonename": m["zonename"]}).Set(swapCap)
-	e.ZoneKstatSwapFree.With(prometheus.Labels{"zonename": m["zonename"]}).Set(swapFree)
-	e.ZoneKstatSwapUsed.With(prometheus.Labels{"zonename": m["zonename"]}).Set(swapUsed)
+		e.ZoneKstatSwapCap.With(labels).Set(swapCap)
+		e.ZoneKstatSwapFree.With(labels).Set(swapFree)
+		e.ZoneKstatSwapUsed.With(labels).Set(swapUsed)
 
+		if err := setKstatVecs(e.ZoneKstatMemPaging, memoryCapKstatStats, k, labels); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (e *ZoneKstatCollector) parseKstatNICListOutput(out string) error {
-	// trim the label in order to obtain the type of metric and interface name
-	r, _ := regexp.Compile(`(?m)^.+:(.+):`)
-
-	outlines := strings.Split(out, "\n")
-	l := len(outlines)
-	m := make(map[ZoneKstatNIC]string)
-	for _, line := range outlines[1 : l-1] {
-		parsedLine := strings.Fields(line)
-		fullLabel := parsedLine[0]
-		ifname := r.FindStringSubmatch(fullLabel)[1]
-		label := r.ReplaceAllString(fullLabel, "")
-		// map struct label and values
-		m[ZoneKstatNIC{ifname, label}] = parsedLine[1]
-	}
-
-	// populates the slice with the map
-	var ZoneKstatNICkeys ZoneKstatNICs
-	for k := range m {
-		ZoneKstatNICkeys = append(ZoneKstatNICkeys, k)
-	}
-
-	for _, k := range ZoneKstatNICkeys {
-		switch k.ifLabel {
-		case "collisions":
-			collisions, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "collisions"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICCollisions.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(collisions)
-		case "ierrors":
-			ierrors, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "ierrors"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICIErrors.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(ierrors)
-		case "ipackets64":
-			ipackets64, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "ipackets64"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICIPackets.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(ipackets64)
-		case "link_state":
-			linkState, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "link_state"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICLinkState.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(linkState)
-		case "obytes64":
-			obytes64, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "obytes64"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICOBytes.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(obytes64)
-		case "oerrors":
-			oerrors, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "oerrors"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICOErrors.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(oerrors)
-		case "opackets64":
-			opackets64, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "opackets64"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICOPackets.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(opackets64)
-		case "rbytes64":
-			rbytes64, err := strconv.ParseFloat(m[ZoneKstatNIC{k.ifName, "rbytes64"}], 64)
-			if err != nil {
-				return err
-			}
-			e.ZoneKstatNICRBytes.With(
-				prometheus.Labels{"zonename": m[ZoneKstatNIC{k.ifName, "zonename"}], "device": k.ifName},
-			).Set(rbytes64)
+	for _, k := range parseKstatOutput(out) {
+		labels := prometheus.Labels{"zonename": k.stats["zonename"], "device": k.name}
+		if err := setKstatVecs(e.ZoneKstatNIC, zoneNICKstatStats, k, labels); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
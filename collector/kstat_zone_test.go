@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const kstatZoneMemoryCapOutput = `memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:anon_alloc_fail	2
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:anonpgin	120
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:class	zone_memory_cap
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:crtime	1021.511012476
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:execpgin	30
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:fspgin	50
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:nover	7
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:pagedout	1048576
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:pgpgin	200
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:physcap	1073741824
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:rss	268435456
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:snaptime	86400.123456789
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:swap	536870912
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:swapcap	2147483648
+memory_cap:3:b2c6f7e4-2b1c-4a5e-9f3:zonename	b2c6f7e4-2b1c-4a5e-9f3e-0123456789ab
+`
+
+func TestParseKstatMemListOutput(t *testing.T) {
+	e, err := NewZoneKstatExporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.parseKstatMemListOutput(kstatZoneMemoryCapOutput); err != nil {
+		t.Fatal(err)
+	}
+
+	zoneName := "b2c6f7e4-2b1c-4a5e-9f3e-0123456789ab"
+	gauges := []struct {
+		name  string
+		gauge *prometheus.GaugeVec
+		want  float64
+	}{
+		{"swap free", e.ZoneKstatSwapFree, 2147483648 - 536870912},
+		{"swap used", e.ZoneKstatSwapUsed, 536870912},
+		{"memory free", e.ZoneKstatMemFree, 1073741824 - 268435456},
+		{"memory rss", e.ZoneKstatMemRSS, 268435456},
+	}
+	for _, g := range gauges {
+		if got := testutil.ToFloat64(g.gauge.WithLabelValues(zoneName)); got != g.want {
+			t.Errorf("%s: got %v, want %v", g.name, got, g.want)
+		}
+	}
+
+	paging := map[string]float64{
+		"nover":           7,
+		"pagedout":        1048576,
+		"anon_alloc_fail": 2,
+		"anonpgin":        120,
+		"execpgin":        30,
+		"fspgin":          50,
+		"pgpgin":          200,
+	}
+	for stat, want := range paging {
+		ch := make(chan prometheus.Metric, 1)
+		e.ZoneKstatMemPaging[stat].Collect(ch)
+		close(ch)
+		m, ok := <-ch
+		if !ok {
+			t.Errorf("%s: no series", stat)
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		if pb.Counter == nil {
+			t.Errorf("%s: not a counter", stat)
+			continue
+		}
+		if got := pb.Counter.GetValue(); got != want {
+			t.Errorf("%s: got %v, want %v", stat, got, want)
+		}
+	}
+}
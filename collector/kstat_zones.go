@@ -0,0 +1,150 @@
+// kstat zones collector
+// this will :
+//  - call kstat on the zones module
+//  - gather per zone CPU time, process and load metrics (every zone from the
+//    GZ, itself from a zone)
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strconv"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// zonesKstatStats lists the statistics of the zones kstats exported as is.
+var zonesKstatStats = []kstatStat{
+	{"boot_time", "boot_time_seconds", "Zone boot time in seconds since the epoch.", prometheus.GaugeValue},
+	{"init_pid", "init_pid", "PID of the zone init process.", prometheus.GaugeValue},
+	{"nprocs", "processes", "Number of processes running in the zone.", prometheus.GaugeValue},
+}
+
+// zonesKstatLoadStats lists the load average statistics of the zones kstats,
+// which are fixed point numbers scaled by zonesKstatFScale.
+var zonesKstatLoadStats = []kstatStat{
+	{"avenrun_1min", "load1", "Zone 1m load average.", prometheus.GaugeValue},
+	{"avenrun_5min", "load5", "Zone 5m load average.", prometheus.GaugeValue},
+	{"avenrun_15min", "load15", "Zone 15m load average.", prometheus.GaugeValue},
+}
+
+// zonesKstatFScale is the scale of the zone load averages (FSCALE).
+const zonesKstatFScale = 256
+
+// ZonesKstatCollector declares the data type within the prometheus metrics
+// package.
+type ZonesKstatCollector struct {
+	ZonesKstatCPUSeconds     *kstatVec
+	ZonesKstatCPUWaitSeconds *kstatVec
+	ZonesKstatLoad           map[string]*kstatVec
+	ZonesKstatMisc           map[string]*kstatVec
+}
+
+// NewZonesKstatExporter returns a newly allocated exporter ZonesKstatCollector.
+// It exposes the CPU time consumed by the zones, their boot time, process
+// count and load averages.
+func NewZonesKstatExporter() (*ZonesKstatCollector, error) {
+	return &ZonesKstatCollector{
+		ZonesKstatCPUSeconds: newKstatVec("smartos_zone_cpu_seconds_total",
+			"CPU time spent by the zone in seconds.",
+			prometheus.CounterValue, []string{"zonename", "mode"}),
+		ZonesKstatCPUWaitSeconds: newKstatVec("smartos_zone_cpu_wait_seconds_total",
+			"Time spent by the zone threads waiting on a run queue in seconds.",
+			prometheus.CounterValue, []string{"zonename"}),
+		ZonesKstatLoad: newKstatVecs(zonesKstatLoadStats, "smartos_zone_", []string{"zonename"}),
+		ZonesKstatMisc: newKstatVecs(zonesKstatStats, "smartos_zone_", []string{"zonename"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *ZonesKstatCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.ZonesKstatCPUSeconds.Describe(ch)
+	e.ZonesKstatCPUWaitSeconds.Describe(ch)
+	for _, s := range zonesKstatLoadStats {
+		e.ZonesKstatLoad[s.stat].Describe(ch)
+	}
+	for _, s := range zonesKstatStats {
+		e.ZonesKstatMisc[s.stat].Describe(ch)
+	}
+}
+
+// Collect fetches the stats.
+func (e *ZonesKstatCollector) Collect(ch chan<- prometheus.Metric) {
+	e.kstatZonesList()
+	e.ZonesKstatCPUSeconds.Collect(ch)
+	e.ZonesKstatCPUWaitSeconds.Collect(ch)
+	for _, s := range zonesKstatLoadStats {
+		e.ZonesKstatLoad[s.stat].Collect(ch)
+	}
+	for _, s := range zonesKstatStats {
+		e.ZonesKstatMisc[s.stat].Collect(ch)
+	}
+}
+
+func (e *ZonesKstatCollector) kstatZonesList() {
+	out, eerr := exec.Command("kstat", "-p", "-m", "zones").Output()
+	if eerr != nil {
+		log.Errorf("error on executing kstat: %v", eerr)
+		return
+	}
+	perr := e.parseKstatZonesListOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing kstat zones list: %v", perr)
+	}
+}
+
+func (e *ZonesKstatCollector) parseKstatZonesListOutput(out string) error {
+	// zones come and go, start from a clean state
+	e.ZonesKstatCPUSeconds.Reset()
+	e.ZonesKstatCPUWaitSeconds.Reset()
+	for _, vec := range e.ZonesKstatLoad {
+		vec.Reset()
+	}
+	for _, vec := range e.ZonesKstatMisc {
+		vec.Reset()
+	}
+
+	for _, k := range parseKstatOutput(out) {
+		// the kstat name is a truncated zone name
+		zoneName, ok := k.stats["zonename"]
+		if !ok {
+			continue
+		}
+
+		nsecUser, err := strconv.ParseFloat(k.stats["nsec_user"], 64)
+		if err != nil {
+			return err
+		}
+		nsecSys, err := strconv.ParseFloat(k.stats["nsec_sys"], 64)
+		if err != nil {
+			return err
+		}
+		nsecWaitRQ, err := strconv.ParseFloat(k.stats["nsec_waitrq"], 64)
+		if err != nil {
+			return err
+		}
+
+		e.ZonesKstatCPUSeconds.Set(prometheus.Labels{"zonename": zoneName, "mode": "user"}, nsecUser/1e9)
+		e.ZonesKstatCPUSeconds.Set(prometheus.Labels{"zonename": zoneName, "mode": "system"}, nsecSys/1e9)
+		e.ZonesKstatCPUWaitSeconds.Set(prometheus.Labels{"zonename": zoneName}, nsecWaitRQ/1e9)
+
+		labels := prometheus.Labels{"zonename": zoneName}
+		for _, s := range zonesKstatLoadStats {
+			v, ok := k.stats[s.stat]
+			if !ok {
+				continue
+			}
+			load, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			e.ZonesKstatLoad[s.stat].Set(labels, load/zonesKstatFScale)
+		}
+		if err := setKstatVecs(e.ZonesKstatMisc, zonesKstatStats, k, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
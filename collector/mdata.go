@@ -0,0 +1,158 @@
+// mdata collector
+// this will :
+//  - call mdata-get inside a zone on an interval
+//  - gather the configured metadata keys
+//  - feed the collector (and optionally label every metric with them)
+
+package collector
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+// mdataKeyLabels maps the SDC metadata keys to the label names used by the
+// vmadm collector in the GZ.
+var mdataKeyLabels = map[string]string{
+	"sdc:alias":      "alias",
+	"sdc:owner_uuid": "owner_uuid",
+}
+
+// ZoneMdataCollector declares the data type within the prometheus metrics
+// package.
+type ZoneMdataCollector struct {
+	zoneMdataInfo *prometheus.GaugeVec
+
+	keys     []string
+	names    []string
+	zoneName string
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewZoneMdataExporter returns a newly allocated exporter ZoneMdataCollector.
+// It exposes the given metadata keys of the zone as labels of an info metric,
+// the label names being the keys with the characters not allowed in a label
+// name replaced by "_" (sdc:alias and sdc:owner_uuid are exposed as alias
+// and owner_uuid). Empty keys are ignored. The values are refreshed every
+// interval.
+func NewZoneMdataExporter(keys []string, interval time.Duration) (*ZoneMdataCollector, error) {
+	var mdataKeys, names []string
+	seen := map[string]bool{"zonename": true}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		name := mdataLabelName(key)
+		// names starting with "__" are reserved for internal use
+		if name == "" || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("metadata key %q is not a valid label name", key)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("metadata key %q conflicts with another label", key)
+		}
+		seen[name] = true
+		mdataKeys = append(mdataKeys, key)
+		names = append(names, name)
+	}
+
+	out, eerr := exec.Command("zonename").Output()
+	if eerr != nil {
+		return nil, eerr
+	}
+
+	e := &ZoneMdataCollector{
+		zoneMdataInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_metadata_info",
+			Help: "Zone metadata from mdata-get; always 1.",
+		}, append([]string{"zonename"}, names...)),
+		keys:     mdataKeys,
+		names:    names,
+		zoneName: strings.TrimSpace(string(out)),
+		values:   make(map[string]string),
+	}
+	e.mdataGet()
+	go func() {
+		for range time.Tick(interval) {
+			e.mdataGet()
+		}
+	}()
+	return e, nil
+}
+
+// Describe describes all the metrics.
+func (e *ZoneMdataCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.zoneMdataInfo.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *ZoneMdataCollector) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	labels := prometheus.Labels{"zonename": e.zoneName}
+	for _, name := range e.names {
+		labels[name] = e.values[name]
+	}
+	e.mu.RUnlock()
+
+	e.zoneMdataInfo.Reset()
+	e.zoneMdataInfo.With(labels).Set(1)
+	e.zoneMdataInfo.Collect(ch)
+}
+
+// Gatherer wraps gatherer so that every gathered metric is labelled with the
+// metadata of the zone.
+func (e *ZoneMdataCollector) Gatherer(gatherer prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := gatherer.Gather()
+
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		for _, mf := range mfs {
+			for _, m := range mf.Metric {
+				injectLabels(m, e.values)
+			}
+		}
+		return mfs, err
+	})
+}
+
+func (e *ZoneMdataCollector) mdataGet() {
+	values := make(map[string]string)
+	for i, key := range e.keys {
+		// mdata-get fails on a key which is not set
+		out, eerr := exec.Command("mdata-get", key).Output()
+		if eerr != nil {
+			log.Debugf("error on executing mdata-get %s: %v", key, eerr)
+			continue
+		}
+		values[e.names[i]] = strings.TrimSpace(string(out))
+	}
+
+	e.mu.Lock()
+	e.values = values
+	e.mu.Unlock()
+}
+
+// mdataLabelName converts a metadata key (e.g. "user:role") into a label name
+// (e.g. "user_role").
+func mdataLabelName(key string) string {
+	if name, ok := mdataKeyLabels[key]; ok {
+		return name
+	}
+	name := []byte(key)
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,153 @@
+// netstat collector
+// this will :
+//  - call netstat (through zlogin for every zone when running in the GZ)
+//  - gather TCP connection states and listening sockets
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// TCPConnCollector declares the data type within the prometheus metrics
+// package.
+type TCPConnCollector struct {
+	tcpConnections *prometheus.GaugeVec
+	tcpListenInfo  *prometheus.GaugeVec
+
+	allZones    bool
+	listenPorts *regexp.Regexp
+}
+
+// NewTCPConnExporter returns a newly allocated exporter TCPConnCollector.
+// It exposes the number of TCP connections by state and the listening
+// sockets whose port fully matches listenPorts. When allZones is set, the
+// connections of every running zone are reported.
+func NewTCPConnExporter(allZones bool, listenPorts string) (*TCPConnCollector, error) {
+	ports, err := regexp.Compile("^(?:" + listenPorts + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &TCPConnCollector{
+		tcpConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_tcp_connections",
+			Help: "Number of TCP connections by state.",
+		}, []string{"zonename", "state"}),
+		tcpListenInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_tcp_listen_info",
+			Help: "TCP listening socket; always 1.",
+		}, []string{"zonename", "family", "address", "port"}),
+		allZones:    allZones,
+		listenPorts: ports,
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *TCPConnCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.tcpConnections.Describe(ch)
+	e.tcpListenInfo.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *TCPConnCollector) Collect(ch chan<- prometheus.Metric) {
+	e.netstat()
+	e.tcpConnections.Collect(ch)
+	e.tcpListenInfo.Collect(ch)
+}
+
+func (e *TCPConnCollector) netstat() {
+	// connections and zones come and go, start from a clean state
+	e.tcpConnections.Reset()
+	e.tcpListenInfo.Reset()
+
+	if !e.allZones {
+		zoneName, eerr := exec.Command("zonename").Output()
+		if eerr != nil {
+			log.Errorf("error on executing zonename: %v", eerr)
+			return
+		}
+		e.netstatZone(strings.TrimSpace(string(zoneName)), "netstat")
+		return
+	}
+
+	out, eerr := exec.Command("zoneadm", "list", "-p").Output()
+	if eerr != nil {
+		log.Errorf("error on executing zoneadm: %v", eerr)
+		return
+	}
+	for _, z := range parseZoneadmListOutput(string(out)) {
+		switch {
+		case z.name == "global":
+			e.netstatZone(z.name, "netstat")
+		case z.state != "running":
+			continue
+		case z.brand == "lx":
+			// the native tools are mounted under /native in LX zones
+			e.netstatZone(z.name, "zlogin", z.name, "/native/usr/bin/netstat")
+		case z.brand == "joyent" || z.brand == "joyent-minimal":
+			e.netstatZone(z.name, "zlogin", z.name, "/usr/bin/netstat")
+		}
+	}
+}
+
+func (e *TCPConnCollector) netstatZone(zoneName string, command ...string) {
+	args := append(command[1:], "-an", "-P", "tcp")
+	out, eerr := exec.Command(command[0], args...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing netstat in zone %s: %v", zoneName, eerr)
+		return
+	}
+	perr := e.parseNetstatOutput(zoneName, string(out))
+	if perr != nil {
+		log.Errorf("error on parsing netstat: %v", perr)
+	}
+}
+
+func (e *TCPConnCollector) parseNetstatOutput(zoneName string, out string) error {
+	// connection lines end with the state, IPv6 ones may add the
+	// interface afterwards
+	r, _ := regexp.Compile(`^[A-Z0-9_]+$`)
+
+	counts := make(map[string]float64)
+	family := ""
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Fields(line)
+		// the IPv4 and IPv6 sockets are listed in their own section,
+		// e.g. "TCP: IPv4"
+		if len(parsedLine) == 2 && parsedLine[0] == "TCP:" {
+			family = strings.ToLower(parsedLine[1])
+			continue
+		}
+		if len(parsedLine) < 7 || !r.MatchString(parsedLine[6]) {
+			continue
+		}
+		state := parsedLine[6]
+		counts[state]++
+
+		if state != "LISTEN" {
+			continue
+		}
+		// the local address is printed as address.port
+		local := parsedLine[0]
+		sep := strings.LastIndex(local, ".")
+		if sep < 0 {
+			continue
+		}
+		address, port := local[:sep], local[sep+1:]
+		if !e.listenPorts.MatchString(port) {
+			continue
+		}
+		e.tcpListenInfo.With(prometheus.Labels{"zonename": zoneName, "family": family, "address": address, "port": port}).Set(1)
+	}
+
+	for state, count := range counts {
+		e.tcpConnections.With(prometheus.Labels{"zonename": zoneName, "state": state}).Set(count)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+// poolstat collector
+// this will :
+//  - call poolstat (or psrset without resource pools), psrinfo and poolbind
+//    inside the GZ
+//  - gather processor set metrics and the zone bindings
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// gzPsetDefault is the ID of the default processor set (PS_NONE), which owns
+// every CPU not assigned to another processor set.
+const gzPsetDefault = "-1"
+
+// GZPsetCollector declares the data type within the prometheus metrics
+// package.
+type GZPsetCollector struct {
+	gzCPUPset  *prometheus.GaugeVec
+	gzPsetInfo *prometheus.GaugeVec
+	gzPsetLoad *prometheus.GaugeVec
+	gzPsetMax  *prometheus.GaugeVec
+	gzPsetMin  *prometheus.GaugeVec
+	gzPsetSize *prometheus.GaugeVec
+	gzPsetUsed *prometheus.GaugeVec
+	gzZonePool *prometheus.GaugeVec
+}
+
+// GZPset defines the mapping of a poolstat -r pset line.
+type GZPset struct {
+	pool, id, name             string
+	min, max, size, used, load float64
+}
+
+// NewGZPsetExporter returns a newly allocated exporter GZPsetCollector.
+// It exposes the size, the load and the CPUs of the processor sets, and the
+// resource pool every running zone is bound to.
+func NewGZPsetExporter() (*GZPsetCollector, error) {
+	return &GZPsetCollector{
+		gzCPUPset: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_cpu_pset_info",
+			Help: "Processor set of the CPU; always 1.",
+		}, []string{"cpu", "pset"}),
+		gzPsetInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_info",
+			Help: "Processor set name and resource pool; always 1.",
+		}, []string{"pset", "name", "pool"}),
+		gzPsetLoad: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_load",
+			Help: "Processor set load average.",
+		}, []string{"pset"}),
+		gzPsetMax: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_max_cpus",
+			Help: "Maximum number of CPUs of the processor set.",
+		}, []string{"pset"}),
+		gzPsetMin: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_min_cpus",
+			Help: "Minimum number of CPUs of the processor set.",
+		}, []string{"pset"}),
+		gzPsetSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_cpus",
+			Help: "Number of CPUs of the processor set.",
+		}, []string{"pset"}),
+		gzPsetUsed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_pset_used_cpus",
+			Help: "CPU time used by the processor set in number of CPUs.",
+		}, []string{"pset"}),
+		gzZonePool: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_pool_info",
+			Help: "Resource pool and processor set the zone is bound to; always 1.",
+		}, []string{"zonename", "pool", "pset"}),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZPsetCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzCPUPset.Describe(ch)
+	e.gzPsetInfo.Describe(ch)
+	e.gzPsetLoad.Describe(ch)
+	e.gzPsetMax.Describe(ch)
+	e.gzPsetMin.Describe(ch)
+	e.gzPsetSize.Describe(ch)
+	e.gzPsetUsed.Describe(ch)
+	e.gzZonePool.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZPsetCollector) Collect(ch chan<- prometheus.Metric) {
+	e.poolstat()
+	e.gzCPUPset.Collect(ch)
+	e.gzPsetInfo.Collect(ch)
+	e.gzPsetLoad.Collect(ch)
+	e.gzPsetMax.Collect(ch)
+	e.gzPsetMin.Collect(ch)
+	e.gzPsetSize.Collect(ch)
+	e.gzPsetUsed.Collect(ch)
+	e.gzZonePool.Collect(ch)
+}
+
+func (e *GZPsetCollector) poolstat() {
+	// processor sets and bindings come and go, start from a clean state
+	e.gzCPUPset.Reset()
+	e.gzPsetInfo.Reset()
+	e.gzPsetLoad.Reset()
+	e.gzPsetMax.Reset()
+	e.gzPsetMin.Reset()
+	e.gzPsetSize.Reset()
+	e.gzPsetUsed.Reset()
+	e.gzZonePool.Reset()
+
+	out, eerr := exec.Command("psrinfo").Output()
+	if eerr != nil {
+		log.Errorf("error on executing psrinfo: %v", eerr)
+		return
+	}
+	cpus := parsePsrinfoOutput(string(out))
+
+	out, eerr = exec.Command("psrset", "-i").Output()
+	if eerr != nil {
+		log.Errorf("error on executing psrset: %v", eerr)
+		return
+	}
+	psets := parsePsrsetOutput(string(out))
+	for _, cpu := range cpus {
+		pset, ok := psets[cpu]
+		if !ok {
+			pset = gzPsetDefault
+		}
+		e.gzCPUPset.With(prometheus.Labels{"cpu": cpu, "pset": pset}).Set(1)
+	}
+
+	// poolstat fails when the resource pools facility is disabled
+	out, eerr = exec.Command("poolstat", "-r", "pset", "-o", "pool,rid,rset,min,max,size,used,load").Output()
+	if eerr != nil {
+		log.Debugf("error on executing poolstat, falling back to psrset: %v", eerr)
+		e.setPsrsetSizes(cpus, psets)
+		return
+	}
+	pools, perr := parsePoolstatOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing poolstat: %v", perr)
+		return
+	}
+	for _, p := range pools {
+		e.gzPsetInfo.With(prometheus.Labels{"pset": p.id, "name": p.name, "pool": p.pool}).Set(1)
+		labels := prometheus.Labels{"pset": p.id}
+		e.gzPsetLoad.With(labels).Set(p.load)
+		e.gzPsetMax.With(labels).Set(p.max)
+		e.gzPsetMin.With(labels).Set(p.min)
+		e.gzPsetSize.With(labels).Set(p.size)
+		e.gzPsetUsed.With(labels).Set(p.used)
+	}
+
+	e.poolbind(pools)
+}
+
+func (e *GZPsetCollector) setPsrsetSizes(cpus []string, psets map[string]string) {
+	sizes := map[string]float64{gzPsetDefault: 0}
+	for _, cpu := range cpus {
+		pset, ok := psets[cpu]
+		if !ok {
+			pset = gzPsetDefault
+		}
+		sizes[pset]++
+	}
+	for pset, size := range sizes {
+		e.gzPsetSize.With(prometheus.Labels{"pset": pset}).Set(size)
+	}
+}
+
+func (e *GZPsetCollector) poolbind(pools map[string]*GZPset) {
+	out, eerr := exec.Command("zoneadm", "list", "-p").Output()
+	if eerr != nil {
+		log.Errorf("error on executing zoneadm: %v", eerr)
+		return
+	}
+	zoneNames := make(map[string]string)
+	args := []string{"-q", "-i", "zoneid"}
+	for _, z := range parseZoneadmListOutput(string(out)) {
+		zoneNames[z.id] = z.name
+		args = append(args, z.id)
+	}
+	if len(zoneNames) == 0 {
+		return
+	}
+
+	out, eerr = exec.Command("poolbind", args...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing poolbind: %v", eerr)
+		return
+	}
+	// e.g. "3	pool_default"
+	for _, line := range strings.Split(string(out), "\n") {
+		parsedLine := strings.Fields(line)
+		if len(parsedLine) != 2 {
+			continue
+		}
+		zoneName, ok := zoneNames[parsedLine[0]]
+		if !ok {
+			continue
+		}
+		pset := ""
+		if p, ok := pools[parsedLine[1]]; ok {
+			pset = p.id
+		}
+		e.gzZonePool.With(prometheus.Labels{"zonename": zoneName, "pool": parsedLine[1], "pset": pset}).Set(1)
+	}
+}
+
+// parsePoolstatOutput maps each pool of
+// poolstat -r pset -o pool,rid,rset,min,max,size,used,load to its processor
+// set.
+func parsePoolstatOutput(out string) (map[string]*GZPset, error) {
+	pools := make(map[string]*GZPset)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Fields(line)
+		// skip the header
+		if len(parsedLine) != 8 || parsedLine[1] == "rid" {
+			continue
+		}
+		var values [5]float64
+		for i := range values {
+			value, err := parsePoolstatValue(parsedLine[i+3])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		pools[parsedLine[0]] = &GZPset{
+			pool: parsedLine[0],
+			id:   parsedLine[1],
+			name: parsedLine[2],
+			min:  values[0],
+			max:  values[1],
+			size: values[2],
+			used: values[3],
+			load: values[4],
+		}
+	}
+	return pools, nil
+}
+
+// parsePoolstatValue converts a poolstat value, scaled down with a K, M, G or
+// T suffix when too large (e.g. "66K"), into a number.
+func parsePoolstatValue(value string) (float64, error) {
+	unit := 1.0
+	if i := strings.IndexAny(value, "KMGT"); i > 0 && i == len(value)-1 {
+		for _, u := range "KMGT" {
+			unit *= 1000
+			if rune(value[i]) == u {
+				break
+			}
+		}
+		value = value[:i]
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * unit, nil
+}
+
+// parsePsrsetOutput maps each CPU of psrset -i to its processor set, e.g.
+// "user processor set 1: processors 2 3".
+func parsePsrsetOutput(out string) map[string]string {
+	r, _ := regexp.Compile(`^\S+ processor set (\d+):(?: processors? ([\d ]+))?`)
+
+	psets := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := r.FindStringSubmatch(line)
+		if fields == nil {
+			continue
+		}
+		for _, cpu := range strings.Fields(fields[2]) {
+			psets[cpu] = fields[1]
+		}
+	}
+	return psets
+}
+
+// parsePsrinfoOutput lists the CPUs of psrinfo, e.g.
+// "0	on-line   since 10/01/2026 08:00:00".
+func parsePsrinfoOutput(out string) []string {
+	var cpus []string
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Fields(line)
+		if len(parsedLine) > 0 && isDigits(parsedLine[0]) {
+			cpus = append(cpus, parsedLine[0])
+		}
+	}
+	return cpus
+}
@@ -0,0 +1,138 @@
+// svcs collector
+// this will :
+//  - call svcs (for every zone when running in the GZ)
+//  - gather SMF service states
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// smfStates lists the states an SMF service instance can be in.
+var smfStates = []string{
+	"uninitialized",
+	"offline",
+	"online",
+	"degraded",
+	"maintenance",
+	"disabled",
+	"legacy_run",
+}
+
+// SMFServicesCollector declares the data type within the prometheus metrics
+// package.
+type SMFServicesCollector struct {
+	smfServiceState *prometheus.GaugeVec
+	smfServices     *prometheus.GaugeVec
+
+	allZones bool
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+}
+
+// NewSMFServicesExporter returns a newly allocated exporter SMFServicesCollector.
+// It exposes the state of the SMF services whose FMRI matches include and
+// does not match exclude (an empty exclude matches nothing). When allZones is
+// set, the services of every zone are reported.
+func NewSMFServicesExporter(allZones bool, include, exclude string) (*SMFServicesCollector, error) {
+	inc, err := regexp.Compile(include)
+	if err != nil {
+		return nil, err
+	}
+	var exc *regexp.Regexp
+	if exclude != "" {
+		exc, err = regexp.Compile(exclude)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &SMFServicesCollector{
+		smfServiceState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_smf_service_state",
+			Help: "Current state of the SMF service; always 1.",
+		}, []string{"zonename", "fmri", "state"}),
+		smfServices: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_smf_services",
+			Help: "Number of SMF services by state.",
+		}, []string{"zonename", "state"}),
+		allZones: allZones,
+		include:  inc,
+		exclude:  exc,
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *SMFServicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.smfServiceState.Describe(ch)
+	e.smfServices.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *SMFServicesCollector) Collect(ch chan<- prometheus.Metric) {
+	e.svcs()
+	e.smfServiceState.Collect(ch)
+	e.smfServices.Collect(ch)
+}
+
+func (e *SMFServicesCollector) svcs() {
+	args := []string{"-aH", "-o", "zone,state,fmri"}
+	if e.allZones {
+		args = append([]string{"-Z"}, args...)
+	}
+	out, eerr := exec.Command("svcs", args...).Output()
+	if eerr != nil {
+		log.Errorf("error on executing svcs: %v", eerr)
+		return
+	}
+	perr := e.parseSvcsOutput(string(out))
+	if perr != nil {
+		log.Errorf("error on parsing svcs: %v", perr)
+	}
+}
+
+func (e *SMFServicesCollector) parseSvcsOutput(out string) error {
+	// services and zones come and go, start from a clean state
+	e.smfServiceState.Reset()
+	e.smfServices.Reset()
+
+	counts := make(map[string]map[string]float64)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Fields(line)
+		if len(parsedLine) < 3 {
+			continue
+		}
+		zoneName := parsedLine[0]
+		// a trailing '*' means the service is transitioning
+		state := strings.TrimSuffix(parsedLine[1], "*")
+		fmri := parsedLine[2]
+		if !e.include.MatchString(fmri) || (e.exclude != nil && e.exclude.MatchString(fmri)) {
+			continue
+		}
+
+		if counts[zoneName] == nil {
+			counts[zoneName] = make(map[string]float64)
+			for _, s := range smfStates {
+				counts[zoneName][s] = 0
+			}
+		}
+		counts[zoneName][state]++
+
+		// only the current state is reported, one series per service
+		// instead of one per possible state
+		e.smfServiceState.With(prometheus.Labels{"zonename": zoneName, "fmri": fmri, "state": state}).Set(1)
+	}
+
+	for zoneName, states := range counts {
+		for state, count := range states {
+			e.smfServices.With(prometheus.Labels{"zonename": zoneName, "state": state}).Set(count)
+		}
+	}
+	return nil
+}
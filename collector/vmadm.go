@@ -0,0 +1,119 @@
+// vmadm labels gatherer
+// this will :
+//  - call vmadm list inside the GZ on an interval
+//  - map each zone to its VM alias, owner and brand
+//  - add them as labels to every metric with a zonename label
+
+package collector
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	// Prometheus Go toolset
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+// VMLabelsGatherer wraps a prometheus Gatherer and enriches the gathered
+// metrics with the labels of the VM behind their zonename label.
+type VMLabelsGatherer struct {
+	gatherer prometheus.Gatherer
+
+	mu     sync.RWMutex
+	labels map[string]map[string]string
+}
+
+// NewVMLabelsGatherer returns a newly allocated VMLabelsGatherer around
+// gatherer. It adds the alias, owner_uuid and brand labels to every metric
+// with a zonename label, and refreshes them from vmadm every interval.
+func NewVMLabelsGatherer(gatherer prometheus.Gatherer, interval time.Duration) *VMLabelsGatherer {
+	g := &VMLabelsGatherer{
+		gatherer: gatherer,
+		labels:   make(map[string]map[string]string),
+	}
+	g.vmadmList()
+	go func() {
+		for range time.Tick(interval) {
+			g.vmadmList()
+		}
+	}()
+	return g
+}
+
+// Gather gathers the wrapped metrics and adds the VM labels.
+func (g *VMLabelsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			for _, lp := range m.Label {
+				if lp.GetName() == "zonename" {
+					injectLabels(m, g.labels[lp.GetValue()])
+					break
+				}
+			}
+		}
+	}
+	return mfs, err
+}
+
+func (g *VMLabelsGatherer) vmadmList() {
+	out, eerr := exec.Command("vmadm", "list", "-p", "-o", "uuid,alias,owner_uuid,brand").Output()
+	if eerr != nil {
+		log.Errorf("error on executing vmadm: %v", eerr)
+		return
+	}
+	labels := parseVmadmListOutput(string(out))
+
+	g.mu.Lock()
+	g.labels = labels
+	g.mu.Unlock()
+}
+
+// parseVmadmListOutput maps the zone of each VM of
+// vmadm list -p -o uuid,alias,owner_uuid,brand to its labels.
+func parseVmadmListOutput(out string) map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Split(line, ":")
+		l := len(parsedLine)
+		if l < 4 {
+			continue
+		}
+		// the alias is free text and may contain the separator
+		labels[parsedLine[0]] = map[string]string{
+			"alias":      strings.Join(parsedLine[1:l-2], ":"),
+			"owner_uuid": parsedLine[l-2],
+			"brand":      parsedLine[l-1],
+		}
+	}
+	return labels
+}
+
+// injectLabels adds labels to a gathered metric. Empty labels and labels the
+// metric already has are left out.
+func injectLabels(m *dto.Metric, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	has := make(map[string]bool)
+	for _, lp := range m.Label {
+		has[lp.GetName()] = true
+	}
+	for name, value := range labels {
+		if value == "" || has[name] {
+			continue
+		}
+		m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	sort.Slice(m.Label, func(i, j int) bool {
+		return m.Label[i].GetName() < m.Label[j].GetName()
+	})
+}
@@ -0,0 +1,202 @@
+// zoneadm collector
+// this will :
+//  - parse zoneadm list -p output
+//  - call zoneadm list -cp (and zonecfg) inside the GZ
+//  - gather zone lifecycle metrics
+//  - feed the collector
+
+package collector
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	// Prometheus Go toolset
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// zoneStates lists the states a zone can be in.
+var zoneStates = []string{
+	"configured",
+	"incomplete",
+	"installed",
+	"ready",
+	"running",
+	"shutting_down",
+	"down",
+	"mounted",
+}
+
+// zoneadmZone defines the mapping of a zoneadm list -p line.
+type zoneadmZone struct {
+	id, name, state, path, uuid, brand, ipType string
+}
+
+// parseZoneadmListOutput parses the output of zoneadm list -p (or -cp), one
+// "zoneid:zonename:state:zonepath:uuid:brand:ip-type" per line. The zone ID
+// of a zone which is not running is "-".
+func parseZoneadmListOutput(out string) []zoneadmZone {
+	var zones []zoneadmZone
+	for _, line := range strings.Split(out, "\n") {
+		parsedLine := strings.Split(line, ":")
+		if len(parsedLine) < 7 {
+			continue
+		}
+		zones = append(zones, zoneadmZone{
+			id:     parsedLine[0],
+			name:   parsedLine[1],
+			state:  parsedLine[2],
+			path:   parsedLine[3],
+			uuid:   parsedLine[4],
+			brand:  parsedLine[5],
+			ipType: parsedLine[6],
+		})
+	}
+	return zones
+}
+
+// GZZoneStateCollector declares the data type within the prometheus metrics
+// package.
+type GZZoneStateCollector struct {
+	gzZoneAutobootDown   *prometheus.GaugeVec
+	gzZoneLastTransition *prometheus.GaugeVec
+	gzZoneState          *prometheus.GaugeVec
+	gzZoneTransitions    *prometheus.CounterVec
+
+	mu    sync.Mutex
+	zones map[string]*GZZoneLifecycle
+}
+
+// GZZoneLifecycle defines the state of a zone seen at the last scrape and the
+// transitions counted since the exporter started.
+type GZZoneLifecycle struct {
+	state       string
+	transitions map[[2]string]bool
+}
+
+// NewGZZoneStateExporter returns a newly allocated exporter GZZoneStateCollector.
+// It exposes the state of every configured zone, the state transitions seen
+// between two scrapes and the zones which should have booted but did not.
+func NewGZZoneStateExporter() (*GZZoneStateCollector, error) {
+	return &GZZoneStateCollector{
+		gzZoneAutobootDown: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_autoboot_not_running",
+			Help: "Zone set to autoboot but not running; 1 if so, 0 otherwise.",
+		}, []string{"zonename"}),
+		gzZoneLastTransition: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_state_last_transition_timestamp_seconds",
+			Help: "Time the last zone state transition was seen in seconds since the epoch.",
+		}, []string{"zonename"}),
+		gzZoneState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "smartos_zone_state",
+			Help: "Zone state; 1 for the current state, 0 otherwise.",
+		}, []string{"zonename", "uuid", "brand", "state"}),
+		gzZoneTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "smartos_zone_state_transitions_total",
+			Help: "Number of zone state transitions seen between two scrapes.",
+		}, []string{"zonename", "from", "to"}),
+		zones: make(map[string]*GZZoneLifecycle),
+	}, nil
+}
+
+// Describe describes all the metrics.
+func (e *GZZoneStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	e.gzZoneAutobootDown.Describe(ch)
+	e.gzZoneLastTransition.Describe(ch)
+	e.gzZoneState.Describe(ch)
+	e.gzZoneTransitions.Describe(ch)
+}
+
+// Collect fetches the stats.
+func (e *GZZoneStateCollector) Collect(ch chan<- prometheus.Metric) {
+	e.zoneadmList()
+	e.gzZoneAutobootDown.Collect(ch)
+	e.gzZoneLastTransition.Collect(ch)
+	e.gzZoneState.Collect(ch)
+	e.gzZoneTransitions.Collect(ch)
+}
+
+func (e *GZZoneStateCollector) zoneadmList() {
+	out, eerr := exec.Command("zoneadm", "list", "-cp").Output()
+	if eerr != nil {
+		log.Errorf("error on executing zoneadm: %v", eerr)
+		return
+	}
+	zones := parseZoneadmListOutput(string(out))
+	e.parseZoneadmListCPOutput(zones, time.Now())
+
+	// zones come and go, start from a clean state
+	e.gzZoneAutobootDown.Reset()
+	for _, z := range zones {
+		autobootDown := 0.0
+		if z.state != "running" && z.name != "global" {
+			autoboot, err := zonecfgAutoboot(z.name)
+			if err != nil {
+				log.Errorf("error on executing zonecfg in zone %s: %v", z.name, err)
+				continue
+			}
+			if autoboot {
+				autobootDown = 1
+			}
+		}
+		e.gzZoneAutobootDown.With(prometheus.Labels{"zonename": z.name}).Set(autobootDown)
+	}
+}
+
+func (e *GZZoneStateCollector) parseZoneadmListCPOutput(zones []zoneadmZone, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.gzZoneState.Reset()
+	seen := make(map[string]bool)
+	for _, z := range zones {
+		seen[z.name] = true
+		for _, s := range zoneStates {
+			v := 0.0
+			if s == z.state {
+				v = 1
+			}
+			e.gzZoneState.With(prometheus.Labels{
+				"zonename": z.name, "uuid": z.uuid, "brand": z.brand, "state": s,
+			}).Set(v)
+		}
+
+		zone, ok := e.zones[z.name]
+		if !ok {
+			// the first state seen is not a transition
+			e.zones[z.name] = &GZZoneLifecycle{state: z.state, transitions: make(map[[2]string]bool)}
+			continue
+		}
+		if zone.state == z.state {
+			continue
+		}
+		e.gzZoneTransitions.With(prometheus.Labels{"zonename": z.name, "from": zone.state, "to": z.state}).Inc()
+		e.gzZoneLastTransition.With(prometheus.Labels{"zonename": z.name}).Set(float64(now.Unix()))
+		zone.transitions[[2]string{zone.state, z.state}] = true
+		zone.state = z.state
+	}
+
+	// forget the deleted zones
+	for name, zone := range e.zones {
+		if seen[name] {
+			continue
+		}
+		for t := range zone.transitions {
+			e.gzZoneTransitions.DeleteLabelValues(name, t[0], t[1])
+		}
+		e.gzZoneLastTransition.DeleteLabelValues(name)
+		delete(e.zones, name)
+	}
+}
+
+// zonecfgAutoboot reports whether the autoboot property of a zone is set.
+func zonecfgAutoboot(zoneName string) (bool, error) {
+	out, err := exec.Command("zonecfg", "-z", zoneName, "info", "autoboot").Output()
+	if err != nil {
+		return false, err
+	}
+	// e.g. "autoboot: true"
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "autoboot:")) == "true", nil
+}
@@ -28,6 +28,15 @@ import (
 var (
 	// Global variables
 	listenAddress = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9100").String()
+	smfInclude    = kingpin.Flag("collector.smf.include", "Regexp of SMF service FMRIs to report.").Default(".+").String()
+	smfExclude    = kingpin.Flag("collector.smf.exclude", "Regexp of SMF service FMRIs to ignore.").Default("").String()
+	tcpListenPort = kingpin.Flag("collector.tcp.listen-ports", "Regexp of TCP listening ports to report.").Default(".+").String()
+	ipfRuleHits   = kingpin.Flag("collector.ipf.rule-hits", "Report the hits of every IP Filter rule.").Default("false").Bool()
+	vmLabels      = kingpin.Flag("collector.vmadm.labels", "Add the VM alias, owner_uuid and brand labels to every metric with a zonename label (GZ only).").Default("false").Bool()
+	vmLabelsEvery = kingpin.Flag("collector.vmadm.labels-refresh", "Interval between two refreshes of the VM labels.").Default("5m").Duration()
+	mdataKeys     = kingpin.Flag("collector.mdata.keys", "Comma separated list of metadata keys to report (zone only).").Default("sdc:alias,sdc:owner_uuid").String()
+	mdataLabels   = kingpin.Flag("collector.mdata.labels", "Add the metadata keys as labels to every metric (zone only).").Default("false").Bool()
+	mdataEvery    = kingpin.Flag("collector.mdata.refresh", "Interval between two refreshes of the metadata keys.").Default("5m").Duration()
 )
 
 func init() {
@@ -68,6 +77,31 @@ func main() {
 	loadAvg, _ := collector.NewLoadAverageExporter()
 	prometheus.MustRegister(loadAvg)
 
+	smfServices, err := collector.NewSMFServicesExporter(gz == 1, *smfInclude, *smfExclude)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prometheus.MustRegister(smfServices)
+
+	netstatKstat, _ := collector.NewNetstatKstatExporter()
+	prometheus.MustRegister(netstatKstat)
+
+	tcpConn, err := collector.NewTCPConnExporter(gz == 1, *tcpListenPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prometheus.MustRegister(tcpConn)
+
+	ipFilter, _ := collector.NewIPFilterExporter(gz == 1, *ipfRuleHits)
+	prometheus.MustRegister(ipFilter)
+
+	zonesKstat, _ := collector.NewZonesKstatExporter()
+	prometheus.MustRegister(zonesKstat)
+	capsKstat, _ := collector.NewCapsKstatExporter()
+	prometheus.MustRegister(capsKstat)
+
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+
 	if gz == 0 {
 		// Zone metrics
 		zoneDf, _ := collector.NewZoneDfExporter()
@@ -75,6 +109,17 @@ func main() {
 
 		zoneKstat, _ := collector.NewZoneKstatExporter()
 		prometheus.MustRegister(zoneKstat)
+
+		if *mdataKeys != "" {
+			zoneMdata, err := collector.NewZoneMdataExporter(strings.Split(*mdataKeys, ","), *mdataEvery)
+			if err != nil {
+				log.Fatal(err)
+			}
+			prometheus.MustRegister(zoneMdata)
+			if *mdataLabels {
+				gatherer = zoneMdata.Gatherer(gatherer)
+			}
+		}
 	}
 
 	if gz == 1 {
@@ -82,24 +127,63 @@ func main() {
 		gzFreeMem, _ := collector.NewGZFreeMemExporter()
 		prometheus.MustRegister(gzFreeMem)
 
-		gzMLAGUsage, _ := collector.NewGZMLAGUsageExporter()
-		prometheus.MustRegister(gzMLAGUsage)
+		gzLinkKstat, _ := collector.NewGZLinkKstatExporter()
+		prometheus.MustRegister(gzLinkKstat)
+
+		gzVNICKstat, _ := collector.NewGZVNICKstatExporter()
+		prometheus.MustRegister(gzVNICKstat)
+
+		gzBandwidth, _ := collector.NewGZBandwidthExporter()
+		prometheus.MustRegister(gzBandwidth)
+
+		gzPhysLink, _ := collector.NewGZPhysLinkExporter()
+		prometheus.MustRegister(gzPhysLink)
+
+		gzAggr, _ := collector.NewGZAggrExporter()
+		prometheus.MustRegister(gzAggr)
+
+		gzEtherKstat, _ := collector.NewGZEtherKstatExporter()
+		prometheus.MustRegister(gzEtherKstat)
+
+		gzOverlay, _ := collector.NewGZOverlayExporter()
+		prometheus.MustRegister(gzOverlay)
 
 		cpuUsage, _ := collector.NewGZCPUUsageExporter()
 		prometheus.MustRegister(cpuUsage)
 
+		gzCPUKstat, _ := collector.NewGZCPUKstatExporter()
+		prometheus.MustRegister(gzCPUKstat)
+
+		gzCPUInfo, _ := collector.NewGZCPUInfoExporter()
+		prometheus.MustRegister(gzCPUInfo)
+
+		gzPset, _ := collector.NewGZPsetExporter()
+		prometheus.MustRegister(gzPset)
+
 		gzDiskErrors, _ := collector.NewGZDiskErrorsExporter()
 		prometheus.MustRegister(gzDiskErrors)
 
 		gzZpoolList, _ := collector.NewGZZpoolListExporter()
 		prometheus.MustRegister(gzZpoolList)
+
+		gzFMErrors, _ := collector.NewGZFMErrorsExporter()
+		prometheus.MustRegister(gzFMErrors)
+
+		gzZoneState, _ := collector.NewGZZoneStateExporter()
+		prometheus.MustRegister(gzZoneState)
+
+		if *vmLabels {
+			gatherer = collector.NewVMLabelsGatherer(gatherer, *vmLabelsEvery)
+		}
 	}
 
 	// The Handler function provides a default handler to expose metrics
 	// via an HTTP server. "/metrics" is the usual endpoint for that.
-	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+	))
 	log.Infoln("Listening on", *listenAddress)
-	err := http.ListenAndServe(*listenAddress, nil)
+	err = http.ListenAndServe(*listenAddress, nil)
 	if err != nil {
 		log.Fatal(err)
 	}